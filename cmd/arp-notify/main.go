@@ -6,13 +6,21 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/discord"
+	"github.com/nekogravitycat/arp-notify/internal/email"
 	"github.com/nekogravitycat/arp-notify/internal/linebot"
 	"github.com/nekogravitycat/arp-notify/internal/monitor"
+	"github.com/nekogravitycat/arp-notify/internal/mqtt"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+	"github.com/nekogravitycat/arp-notify/internal/outbox"
+	"github.com/nekogravitycat/arp-notify/internal/telegram"
 	"github.com/nekogravitycat/arp-notify/internal/web"
+	"github.com/nekogravitycat/arp-notify/internal/webhook"
 )
 
 func main() {
@@ -26,11 +34,43 @@ func main() {
 		log.Fatalf("Failed to load configs: %v", err)
 	}
 
-	if err := linebot.CheckEnv(); err != nil {
-		log.Fatalf("LINE bot configuration error: %v", err)
+	// LINE and Telegram are both optional, but at least one bot must be set up.
+	lineErr := linebot.CheckEnv()
+	telegramToken := os.Getenv("TELEGRAM_BOT_TOKEN")
+	switch {
+	case lineErr != nil && telegramToken == "":
+		log.Fatalf("LINE bot configuration error: %v (or set TELEGRAM_BOT_TOKEN)", lineErr)
+	case lineErr != nil:
+		log.Printf("LINE bot disabled: %v", lineErr)
+	default:
+		notifier.Register(config.ChannelLine, linebot.Notifier{})
+	}
+	if telegramToken != "" {
+		bot := telegram.New(telegramToken)
+		bot.SetCommandHandler(monitor.HandleChatCommand)
+		notifier.Register(config.ChannelTelegram, bot)
+		go bot.Poll(context.Background())
 	}
 
+	notifier.Register(config.ChannelDiscord, discord.New(func() config.DiscordConfig {
+		return config.GetTargetsConfig().Channels.Discord
+	}))
+	notifier.Register(config.ChannelEmail, email.New(func() config.EmailConfig {
+		return config.GetSystemConfig().Email
+	}))
+	notifier.Register(config.ChannelWebhook, webhook.New(func() map[string]config.WebhookConfig {
+		return config.GetTargetsConfig().Channels.Webhooks
+	}))
+	linebot.SetCommandHandler(monitor.HandleChatCommand)
+	publisher := mqtt.New(func() config.MQTTConfig {
+		return config.GetSystemConfig().MQTT
+	})
+	monitor.SetPresenceHandler(publisher.Update)
+	go publisher.Run(context.Background())
+	outbox.SetPauseCheck(monitor.Paused)
+	go outbox.Run(context.Background())
 	go monitor.StartPeriodicScan(context.Background())
+	go monitor.StartWatchdog(context.Background())
 
 	mux := http.NewServeMux()
 	linebot.RegisterRoutes(mux)
@@ -0,0 +1,51 @@
+package arpscan
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Host is one responding host in arp-scan's plain (-x) output.
+type Host struct {
+	IP     string `json:"ip"`
+	MAC    string `json:"mac"`
+	Vendor string `json:"vendor"`
+}
+
+var dupRegex = regexp.MustCompile(`^\(DUP: \d+\)$`)
+
+// ParseOutput extracts the responding hosts from arp-scan -x output. Each line
+// is tab-separated: IP, MAC, then the vendor (absent with -q) and a "(DUP: n)"
+// marker when an IP answered from more than one packet, which is dropped: each
+// reply is its own host, so answers from several MACs show up as such. Lines
+// that don't start with a valid IP and MAC are skipped. MACs are returned
+// lower-cased.
+func ParseOutput(output string) []Host {
+	var hosts []Host
+	for line := range strings.SplitSeq(output, "\n") {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		ip, mac := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])
+		if net.ParseIP(ip) == nil {
+			continue
+		}
+		if _, err := net.ParseMAC(mac); err != nil {
+			continue
+		}
+
+		h := Host{IP: ip, MAC: strings.ToLower(mac)}
+		var vendor []string
+		for _, f := range fields[2:] {
+			f = strings.TrimSpace(f)
+			if f != "" && !dupRegex.MatchString(f) {
+				vendor = append(vendor, f)
+			}
+		}
+		h.Vendor = strings.Join(vendor, " ")
+		hosts = append(hosts, h)
+	}
+	return hosts
+}
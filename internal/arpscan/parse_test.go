@@ -0,0 +1,33 @@
+package arpscan
+
+import "testing"
+
+func TestParseOutput(t *testing.T) {
+	output := "192.168.0.1\tAA:BB:CC:DD:EE:01\tAcme Corp\n" +
+		"192.168.0.2\taa:bb:cc:dd:ee:02\n" +
+		"192.168.0.1\taa:bb:cc:dd:ee:03\tEvil Inc\t(DUP: 2)\n" +
+		"Interface: eth0, type: EN10MB\n" +
+		"\n"
+
+	hosts := ParseOutput(output)
+	if len(hosts) != 3 {
+		t.Fatalf("expected 3 hosts, got %d: %+v", len(hosts), hosts)
+	}
+
+	want := []Host{
+		{IP: "192.168.0.1", MAC: "aa:bb:cc:dd:ee:01", Vendor: "Acme Corp"},
+		{IP: "192.168.0.2", MAC: "aa:bb:cc:dd:ee:02", Vendor: ""},
+		{IP: "192.168.0.1", MAC: "aa:bb:cc:dd:ee:03", Vendor: "Evil Inc"},
+	}
+	for i, w := range want {
+		if hosts[i] != w {
+			t.Errorf("host %d = %+v, want %+v", i, hosts[i], w)
+		}
+	}
+}
+
+func TestParseOutputSkipsGarbage(t *testing.T) {
+	if hosts := ParseOutput("not\tan\tarp line\nfoo\n"); len(hosts) != 0 {
+		t.Errorf("expected no hosts, got %+v", hosts)
+	}
+}
@@ -8,8 +8,9 @@ import (
 // RunArpScan runs arp-scan with a context timeout and returns output and error.
 func RunArpScan(ctx context.Context, bin string, iface string) (string, error) {
 	// Construct full path and args (no shell).
-	// -q and -x makes parsing easier (no header/footer, minimal output)
-	args := []string{"-l", "-q", "-x"}
+	// -x makes parsing easier (no header/footer). Unlike the per-IP scan, -q is
+	// left off so each line keeps the vendor name that new-device alerts report.
+	args := []string{"-l", "-x"}
 
 	if iface != "" {
 		args = append(args, "-I", iface)
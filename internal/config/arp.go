@@ -6,13 +6,19 @@ import (
 	"net"
 	"os/exec"
 	"regexp"
+	"strings"
 )
 
 // SystemConfig holds the system / scan behavior loaded from config.yaml.
 type SystemConfig struct {
-	ArpScan ArpScanConfig `yaml:"arp_scan" json:"arp_scan"`
-	Monitor MonitorConfig `yaml:"monitor" json:"monitor"`
-	Server  ServerConfig  `yaml:"server" json:"server"`
+	ArpScan   ArpScanConfig   `yaml:"arp_scan" json:"arp_scan"`
+	Monitor   MonitorConfig   `yaml:"monitor" json:"monitor"`
+	Inventory InventoryConfig `yaml:"inventory" json:"inventory"`
+	Watchdog  WatchdogConfig  `yaml:"watchdog" json:"watchdog"`
+	Server    ServerConfig    `yaml:"server" json:"server"`
+	Email     EmailConfig     `yaml:"email,omitempty" json:"email"`
+	MQTT      MQTTConfig      `yaml:"mqtt,omitempty" json:"mqtt"`
+	Rules     RulesConfig     `yaml:"rules,omitempty" json:"rules"`
 }
 
 type ArpScanConfig struct {
@@ -24,12 +30,52 @@ type ArpScanConfig struct {
 }
 
 type MonitorConfig struct {
-	AbsenceResetMin int `yaml:"absence_reset_min" json:"absence_reset_min"`
+	AbsenceResetMin   int `yaml:"absence_reset_min" json:"absence_reset_min"`
+	DepartureDelayMin int `yaml:"departure_delay_min" json:"departure_delay_min"`
+	MissThreshold     int `yaml:"miss_threshold" json:"miss_threshold"`
+}
+
+// For returns the presence settings that apply to the target: its own
+// presence_policy overrides where set, the global values otherwise.
+func (m MonitorConfig) For(t Target) MonitorConfig {
+	if t.Policy.AbsenceResetMin > 0 {
+		m.AbsenceResetMin = t.Policy.AbsenceResetMin
+	}
+	if t.Policy.DepartureDelayMin > 0 {
+		m.DepartureDelayMin = t.Policy.DepartureDelayMin
+	}
+	if t.Policy.MissThreshold > 0 {
+		m.MissThreshold = t.Policy.MissThreshold
+	}
+	return m
+}
+
+// InventoryConfig controls the LAN inventory. Enabling it runs the broadcast
+// scan every cycle so every host on the network is recorded.
+type InventoryConfig struct {
+	Enabled    bool `yaml:"enabled" json:"enabled"`
+	ReverseDNS bool `yaml:"reverse_dns" json:"reverse_dns"`
+}
+
+// WatchdogConfig tunes the scan watchdog, which alerts the admins listed in
+// targets.yaml when detection stops working.
+type WatchdogConfig struct {
+	FailedCycles int `yaml:"failed_cycles" json:"failed_cycles"`
 }
 
 type ServerConfig struct {
-	Host string `yaml:"host" json:"host"`
-	Port int    `yaml:"port" json:"port"`
+	Host      string `yaml:"host" json:"host"`
+	Port      int    `yaml:"port" json:"port"`
+	PublicURL string `yaml:"public_url,omitempty" json:"public_url"` // where the admin UI is reachable, for links in notifications
+}
+
+// Link returns the admin UI address for a URL fragment such as "status", or
+// "" without a public URL.
+func (s ServerConfig) Link(fragment string) string {
+	if s.PublicURL == "" {
+		return ""
+	}
+	return strings.TrimRight(s.PublicURL, "/") + "/#" + fragment
 }
 
 // applySystemDefaults fills in sensible defaults for any zero-valued field so
@@ -50,6 +96,15 @@ func applySystemDefaults(cfg *SystemConfig) {
 	if cfg.Monitor.AbsenceResetMin == 0 {
 		cfg.Monitor.AbsenceResetMin = 1440 // 24 hours
 	}
+	if cfg.Monitor.DepartureDelayMin == 0 {
+		cfg.Monitor.DepartureDelayMin = 10
+	}
+	if cfg.Monitor.MissThreshold == 0 {
+		cfg.Monitor.MissThreshold = 3
+	}
+	if cfg.Watchdog.FailedCycles == 0 {
+		cfg.Watchdog.FailedCycles = 3
+	}
 	if cfg.Server.Host == "" {
 		cfg.Server.Host = "127.0.0.1" // loopback only by default; set 0.0.0.0 to expose
 	}
@@ -77,13 +132,28 @@ func validateSystemConfig(cfg *SystemConfig) error {
 	if cfg.Monitor.AbsenceResetMin <= 0 {
 		return errors.New("monitor.absence_reset_min must be > 0")
 	}
+	if cfg.Monitor.DepartureDelayMin <= 0 {
+		return errors.New("monitor.departure_delay_min must be > 0")
+	}
+	if cfg.Monitor.MissThreshold <= 0 {
+		return errors.New("monitor.miss_threshold must be > 0")
+	}
+	if cfg.Watchdog.FailedCycles <= 0 {
+		return errors.New("watchdog.failed_cycles must be > 0")
+	}
 	if net.ParseIP(cfg.Server.Host) == nil {
 		return fmt.Errorf("server.host %q is not a valid IP address", cfg.Server.Host)
 	}
 	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
 		return errors.New("server.port must be between 1 and 65535")
 	}
-	return nil
+	if err := validatePublicURL(cfg.Server.PublicURL); err != nil {
+		return err
+	}
+	if err := validateEmail(cfg.Email); err != nil {
+		return err
+	}
+	return validateMQTT(cfg.MQTT)
 }
 
 // checkBin checks if the arp-scan binary is available in PATH.
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultBatchMessage is the merged message used when batching has none.
+const DefaultBatchMessage = "{{join .Names}} are home"
+
+// maxBatchWindowSec caps the coalescing window; longer waits would make the
+// arrival notifications feel late.
+const maxBatchWindowSec = 600
+
+// Batching merges the arrival notifications a receiver would get within a
+// short window (e.g. the whole family coming home in one car) into one
+// message.
+type Batching struct {
+	WindowSec int    `yaml:"window_sec" json:"window_sec"`     // 0 = send each arrival right away
+	Message   string `yaml:"message,omitempty" json:"message"` // text/template; empty = DefaultBatchMessage
+}
+
+// BatchData is what the batching message template is rendered with.
+type BatchData struct {
+	Names    []string // who arrived, in order
+	Messages []string // the messages that were merged
+	Count    int
+}
+
+// Window returns the coalescing window; zero means batching is off.
+func (b Batching) Window() time.Duration {
+	return time.Duration(b.WindowSec) * time.Second
+}
+
+// Template parses the batching message. Besides the standard functions it
+// offers join, which lists names as "Mom, Dad and Amy".
+func (b Batching) Template() (*template.Template, error) {
+	text := b.Message
+	if text == "" {
+		text = DefaultBatchMessage
+	}
+	return template.New("batch").Funcs(template.FuncMap{"join": joinNames}).Parse(text)
+}
+
+func joinNames(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// sampleBatchData is what the batching message is test-rendered with when it
+// is validated.
+var sampleBatchData = BatchData{
+	Names:    []string{"Mom", "Dad"},
+	Messages: []string{"Mom is home", "Dad is home"},
+	Count:    2,
+}
+
+func validateBatching(b Batching) error {
+	if b.WindowSec < 0 || b.WindowSec > maxBatchWindowSec {
+		return fmt.Errorf("batching: window_sec must be between 0 and %d", maxBatchWindowSec)
+	}
+	tmpl, err := b.Template()
+	if err == nil {
+		err = tmpl.Execute(io.Discard, sampleBatchData)
+	}
+	if err != nil {
+		return fmt.Errorf("batching: invalid message template: %w", err)
+	}
+	return nil
+}
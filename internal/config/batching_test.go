@@ -0,0 +1,45 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBatchingTemplate(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{[]string{"Mom", "Dad"}, "Mom and Dad are home"},
+		{[]string{"Mom", "Dad", "Amy"}, "Mom, Dad and Amy are home"},
+	}
+	for _, tt := range tests {
+		tmpl, err := Batching{}.Template()
+		if err != nil {
+			t.Fatalf("default template: %v", err)
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, BatchData{Names: tt.names}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		if sb.String() != tt.want {
+			t.Errorf("got %q, want %q", sb.String(), tt.want)
+		}
+	}
+}
+
+func TestValidateBatching(t *testing.T) {
+	if err := validateBatching(Batching{WindowSec: 30, Message: "{{.Count}} arrivals: {{join .Names}}"}); err != nil {
+		t.Errorf("valid batching rejected: %v", err)
+	}
+	for _, b := range []Batching{
+		{WindowSec: -1},
+		{WindowSec: maxBatchWindowSec + 1},
+		{WindowSec: 30, Message: "{{join .Names"},
+		{WindowSec: 30, Message: "{{join .Nmae}} are home"},
+	} {
+		if err := validateBatching(b); err == nil {
+			t.Errorf("%+v: expected error", b)
+		}
+	}
+}
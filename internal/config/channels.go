@@ -0,0 +1,207 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Delivery channels a receiver ID can name.
+const (
+	ChannelLine     = "line"
+	ChannelDiscord  = "discord"
+	ChannelTelegram = "telegram"
+	ChannelWebhook  = "webhook"
+	ChannelEmail    = "email"
+)
+
+// channels lists the known delivery channels, in the order they are offered.
+var channels = []string{ChannelLine, ChannelDiscord, ChannelTelegram, ChannelWebhook, ChannelEmail}
+
+// ChannelsConfig configures the delivery channels other than LINE and
+// Telegram, whose credentials come from the environment, and email, which is
+// set up in config.yaml.
+type ChannelsConfig struct {
+	Discord  DiscordConfig            `yaml:"discord,omitempty" json:"discord"`
+	Webhooks map[string]WebhookConfig `yaml:"webhooks,omitempty" json:"webhooks"` // name -> webhook
+}
+
+// DiscordConfig names Discord webhooks; receivers address them as
+// "discord:<name>".
+type DiscordConfig struct {
+	Webhooks map[string]string `yaml:"webhooks,omitempty" json:"webhooks"` // name -> webhook URL
+	Username string            `yaml:"username,omitempty" json:"username"` // overrides the webhook's name
+}
+
+// WebhookConfig is an outgoing HTTP webhook; receivers address it as
+// "webhook:<name>". The body is a text/template rendered with the message.
+type WebhookConfig struct {
+	URL       string            `yaml:"url" json:"url"`
+	Method    string            `yaml:"method,omitempty" json:"method"`         // POST (default), PUT or PATCH
+	Headers   map[string]string `yaml:"headers,omitempty" json:"headers"`       // extra request headers
+	Body      string            `yaml:"body,omitempty" json:"body"`             // empty = the message as JSON
+	SecretEnv string            `yaml:"secret_env,omitempty" json:"secret_env"` // env var holding the HMAC key; empty = unsigned
+}
+
+var webhookMethods = []string{"POST", "PUT", "PATCH"}
+
+// HTTPMethod returns the request method, POST by default.
+func (w WebhookConfig) HTTPMethod() string {
+	if w.Method == "" {
+		return "POST"
+	}
+	return strings.ToUpper(w.Method)
+}
+
+// Template parses the body, or returns nil when the message is sent as JSON.
+// Besides the standard functions it offers json, which encodes a value as
+// JSON so text can be embedded in a JSON body safely: {"text": {{json .Text}}}.
+func (w WebhookConfig) Template() (*template.Template, error) {
+	if w.Body == "" {
+		return nil, nil
+	}
+	return template.New("webhook").Funcs(template.FuncMap{"json": jsonValue}).Parse(w.Body)
+}
+
+func jsonValue(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func validateWebhook(name string, w WebhookConfig) error {
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("channels.webhooks: %q: invalid url", name)
+	}
+	if !slices.Contains(webhookMethods, w.HTTPMethod()) {
+		return fmt.Errorf("channels.webhooks: %q: method must be one of %s", name, strings.Join(webhookMethods, ", "))
+	}
+	t, err := w.Template()
+	if err == nil && t != nil {
+		err = t.Execute(io.Discard, sampleNotification)
+	}
+	if err != nil {
+		return fmt.Errorf("channels.webhooks: %q: invalid body template: %w", name, err)
+	}
+	return nil
+}
+
+// Channels returns the known delivery channel names.
+func Channels() []string {
+	return slices.Clone(channels)
+}
+
+// ParseReceiverID splits a typed receiver ID such as "line:U…" into its
+// channel and the channel-specific address. A bare ID without a known channel
+// prefix is a LINE ID, as in configs written before channels existed.
+func ParseReceiverID(id string) (channel, address string) {
+	if prefix, rest, ok := strings.Cut(id, ":"); ok && slices.Contains(channels, strings.ToLower(prefix)) {
+		return strings.ToLower(prefix), rest
+	}
+	return ChannelLine, id
+}
+
+// SameReceiver reports whether two receiver IDs address the same recipient,
+// e.g. "U123" and "line:U123".
+func SameReceiver(a, b string) bool {
+	ca, aa := ParseReceiverID(a)
+	cb, ab := ParseReceiverID(b)
+	return ca == cb && aa == ab
+}
+
+func (r Receiver) validate() error {
+	if r.ID == "" {
+		return errors.New("empty id")
+	}
+	if prefix, _, ok := strings.Cut(r.ID, ":"); ok && !slices.Contains(channels, strings.ToLower(prefix)) && isChannelName(prefix) {
+		return fmt.Errorf("unknown channel %q in id %q (expected %s)", prefix, r.ID, strings.Join(channels, "|"))
+	}
+	if _, address := ParseReceiverID(r.ID); address == "" {
+		return fmt.Errorf("id %q has an empty address", r.ID)
+	}
+	return nil
+}
+
+// isChannelName reports whether s looks like a channel prefix rather than
+// part of an address.
+func isChannelName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateChannels checks the channel settings and that every receiver
+// addressing a configured channel names something that exists.
+func validateChannels(cfg *TargetsConfig) error {
+	for name, raw := range cfg.Channels.Discord.Webhooks {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("channels.discord: webhook %q: invalid url", name)
+		}
+	}
+	for name, w := range cfg.Channels.Webhooks {
+		if err := validateWebhook(name, w); err != nil {
+			return err
+		}
+	}
+	for _, r := range cfg.AllReceivers() {
+		channel, address := ParseReceiverID(r.ID)
+		switch channel {
+		case ChannelDiscord:
+			if _, ok := cfg.Channels.Discord.Webhooks[address]; !ok {
+				return fmt.Errorf("receiver %q: no discord webhook named %q in channels.discord.webhooks", r.ID, address)
+			}
+		case ChannelWebhook:
+			if _, ok := cfg.Channels.Webhooks[address]; !ok {
+				return fmt.Errorf("receiver %q: no webhook named %q in channels.webhooks", r.ID, address)
+			}
+		case ChannelEmail:
+			// List names are resolved against config.yaml when sending.
+			if strings.Contains(address, "@") {
+				if _, err := mail.ParseAddress(address); err != nil {
+					return fmt.Errorf("receiver %q: %w", r.ID, err)
+				}
+			}
+		case ChannelTelegram:
+			// A numeric chat ID (negative for groups) or a public @channel.
+			if _, err := strconv.ParseInt(address, 10, 64); err != nil && !strings.HasPrefix(address, "@") {
+				return fmt.Errorf("receiver %q: telegram address must be a chat id or @channel", r.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// AllReceivers returns every receiver configured anywhere in the targets
+// config.
+func (cfg TargetsConfig) AllReceivers() []Receiver {
+	var out []Receiver
+	for _, t := range cfg.Targets {
+		out = append(out, t.Receivers...)
+		out = append(out, t.Expect.Receivers...)
+	}
+	for _, p := range cfg.People {
+		out = append(out, p.Receivers...)
+	}
+	out = append(out, cfg.Household.Receivers...)
+	out = append(out, cfg.NewDevices.Receivers...)
+	out = append(out, cfg.Security.Receivers...)
+	out = append(out, cfg.Admins...)
+	for _, rule := range cfg.Rules {
+		for _, a := range rule.Actions {
+			out = append(out, a.Receivers...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+package config
+
+import "testing"
+
+func TestParseReceiverID(t *testing.T) {
+	tests := []struct {
+		id, channel, address string
+	}{
+		{"U123", ChannelLine, "U123"},
+		{"line:U123", ChannelLine, "U123"},
+		{"LINE:U123", ChannelLine, "U123"},
+	}
+	for _, tt := range tests {
+		c, a := ParseReceiverID(tt.id)
+		if c != tt.channel || a != tt.address {
+			t.Errorf("ParseReceiverID(%q) = %q, %q; want %q, %q", tt.id, c, a, tt.channel, tt.address)
+		}
+	}
+	if !SameReceiver("U123", "line:U123") || SameReceiver("U123", "line:U124") {
+		t.Error("SameReceiver mismatch")
+	}
+}
+
+func TestReceiverValidate(t *testing.T) {
+	for _, id := range []string{"U123", "line:U123", "C123", "line:R123"} {
+		if err := (Receiver{ID: id}).validate(); err != nil {
+			t.Errorf("%q rejected: %v", id, err)
+		}
+	}
+	for _, id := range []string{"", "line:", "pager:123"} {
+		if err := (Receiver{ID: id}).validate(); err == nil {
+			t.Errorf("%q: expected error", id)
+		}
+	}
+}
+
+func TestValidateDiscordReceivers(t *testing.T) {
+	tgt := validTarget()
+	tgt.Receivers = []Receiver{{ID: "discord:family"}}
+	cfg := &TargetsConfig{
+		Targets:  []Target{tgt},
+		Channels: ChannelsConfig{Discord: DiscordConfig{Webhooks: map[string]string{"family": "https://discord.com/api/webhooks/1/abc"}}},
+	}
+	if err := validateTargetsConfig(cfg); err != nil {
+		t.Errorf("valid discord receiver rejected: %v", err)
+	}
+
+	cfg.Targets[0].Receivers = []Receiver{{ID: "discord:work"}}
+	if err := validateTargetsConfig(cfg); err == nil {
+		t.Error("receiver naming an unknown webhook should be rejected")
+	}
+
+	cfg.Targets[0].Receivers = []Receiver{{ID: "discord:family"}}
+	cfg.Channels.Discord.Webhooks["family"] = "not a url"
+	if err := validateTargetsConfig(cfg); err == nil {
+		t.Error("invalid webhook url should be rejected")
+	}
+}
+
+func TestValidateWebhooks(t *testing.T) {
+	tgt := validTarget()
+	tgt.Receivers = []Receiver{{ID: "webhook:presence"}}
+	cfg := &TargetsConfig{
+		Targets: []Target{tgt},
+		Channels: ChannelsConfig{Webhooks: map[string]WebhookConfig{
+			"presence": {URL: "https://example.com/hook", Body: `{"text": {{json .Text}}}`},
+		}},
+	}
+	if err := validateTargetsConfig(cfg); err != nil {
+		t.Errorf("valid webhook rejected: %v", err)
+	}
+
+	cfg.Targets[0].Receivers = []Receiver{{ID: "webhook:other"}}
+	if err := validateTargetsConfig(cfg); err == nil {
+		t.Error("receiver naming an unknown webhook should be rejected")
+	}
+	cfg.Targets[0].Receivers = []Receiver{{ID: "webhook:presence"}}
+
+	for _, bad := range []WebhookConfig{
+		{URL: "ftp://example.com"},
+		{URL: "https://example.com", Method: "GET"},
+		{URL: "https://example.com", Body: "{{.Text"},
+		{URL: "https://example.com", Body: "{{.Txet}}"},
+	} {
+		cfg.Channels.Webhooks["presence"] = bad
+		if err := validateTargetsConfig(cfg); err == nil {
+			t.Errorf("%+v should be rejected", bad)
+		}
+	}
+}
@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 	"sync"
 
+	"github.com/nekogravitycat/arp-notify/internal/statefile"
 	"gopkg.in/yaml.v3"
 )
 
@@ -46,7 +46,7 @@ func notifySystemChanged() {
 func Load() error {
 	// System config: seed defaults if missing, then load.
 	if _, err := os.Stat(systemConfigPath); errors.Is(err, os.ErrNotExist) {
-		if err := writeFileAtomic(systemConfigPath, []byte(systemConfigTemplate)); err != nil {
+		if err := statefile.WriteAtomic(systemConfigPath, []byte(systemConfigTemplate)); err != nil {
 			return fmt.Errorf("failed to create %q: %w", systemConfigPath, err)
 		}
 		log.Printf("Created default config file %q.", systemConfigPath)
@@ -63,7 +63,7 @@ func Load() error {
 
 	// Targets config: seed template if missing and ask the user to populate it.
 	if _, err := os.Stat(targetsConfigPath); errors.Is(err, os.ErrNotExist) {
-		if err := writeFileAtomic(targetsConfigPath, []byte(targetsConfigTemplate)); err != nil {
+		if err := statefile.WriteAtomic(targetsConfigPath, []byte(targetsConfigTemplate)); err != nil {
 			return fmt.Errorf("failed to create %q: %w", targetsConfigPath, err)
 		}
 		return fmt.Errorf("created empty config file %q. please populate it and restart the application", targetsConfigPath)
@@ -76,6 +76,9 @@ func Load() error {
 	if err := validateTargetsConfig(&targets); err != nil {
 		return fmt.Errorf("invalid %q: %w", targetsConfigPath, err)
 	}
+	if err := checkRuleCommands(targets.Rules, sys.Rules.AllowCommands); err != nil {
+		return fmt.Errorf("invalid %q: %w", targetsConfigPath, err)
+	}
 
 	mu.Lock()
 	systemCfg = sys
@@ -83,7 +86,7 @@ func Load() error {
 	mu.Unlock()
 
 	log.Printf("Using system config: %+v", sys)
-	log.Printf("Loaded targets config: %d target(s), %d contact(s).", len(targets.Targets), len(targets.Contacts))
+	log.Printf("Loaded targets config: %d target(s), %d person(s), %d contact(s).", len(targets.Targets), len(targets.People), len(targets.Contacts))
 	return nil
 }
 
@@ -111,7 +114,7 @@ func SaveSystemConfig(cfg SystemConfig) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal system config: %w", err)
 	}
-	if err := writeFileAtomic(systemConfigPath, data); err != nil {
+	if err := statefile.WriteAtomic(systemConfigPath, data); err != nil {
 		return err
 	}
 	mu.Lock()
@@ -122,15 +125,19 @@ func SaveSystemConfig(cfg SystemConfig) error {
 }
 
 // SaveTargetsConfig validates, persists, and hot-swaps the targets config.
+// Command actions are only accepted when config.yaml allows them.
 func SaveTargetsConfig(cfg TargetsConfig) error {
 	if err := validateTargetsConfig(&cfg); err != nil {
 		return err
 	}
+	if err := checkRuleCommands(cfg.Rules, GetSystemConfig().Rules.AllowCommands); err != nil {
+		return err
+	}
 	data, err := yaml.Marshal(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal targets config: %w", err)
 	}
-	if err := writeFileAtomic(targetsConfigPath, data); err != nil {
+	if err := statefile.WriteAtomic(targetsConfigPath, data); err != nil {
 		return err
 	}
 	mu.Lock()
@@ -150,32 +157,6 @@ func loadYAMLFile(path string, out any) error {
 	return nil
 }
 
-// writeFileAtomic writes data to a temp file in the same directory and renames
-// it over the destination, so readers never observe a half-written file.
-func writeFileAtomic(path string, data []byte) error {
-	dir := filepath.Dir(path)
-	tmp, err := os.CreateTemp(dir, ".tmp-*.yaml")
-	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
-	}
-	tmpName := tmp.Name()
-
-	if _, err := tmp.Write(data); err != nil {
-		tmp.Close()
-		os.Remove(tmpName)
-		return fmt.Errorf("failed to write temp file: %w", err)
-	}
-	if err := tmp.Close(); err != nil {
-		os.Remove(tmpName)
-		return fmt.Errorf("failed to close temp file: %w", err)
-	}
-	if err := os.Rename(tmpName, path); err != nil {
-		os.Remove(tmpName)
-		return fmt.Errorf("failed to replace %q: %w", path, err)
-	}
-	return nil
-}
-
 const systemConfigTemplate = `# arp-notify system configuration.
 arp_scan:
   bin: arp-scan            # path to the arp-scan binary
@@ -185,14 +166,58 @@ arp_scan:
   individual_timeout_sec: 2
 monitor:
   absence_reset_min: 1440  # re-notify after a device has been absent this long (minutes)
+  departure_delay_min: 10  # a device unseen this long counts as away (minutes)
+  miss_threshold: 3        # ...and only after missing this many scans in a row
+inventory:
+  enabled: false           # record every host on the LAN (broadcast-scans every cycle)
+  reverse_dns: false       # look up hostnames for newly seen IPs
+watchdog:
+  failed_cycles: 3         # alert the admins (targets.yaml) after this many failed or empty scan cycles
 server:
   host: "127.0.0.1"        # bind address; 127.0.0.1 = loopback only, 0.0.0.0 = all interfaces
   port: 5000               # HTTP port for the LINE webhook and the /admin UI
+  public_url: ""           # optional; e.g. https://home.example.com, for links in LINE cards and new-device alerts
+# Email notifications over SMTP; receivers address a list from "to" as
+# "email:<name>" or one address as "email:<address>". The password is read from
+# SMTP_PASSWORD in .env. Subject and body are Go templates over {{.Text}},
+# {{.Kind}}, {{.Subject}}, {{.Mac}}, {{.IP}} and {{.Time}}.
+email:
+  host: ""                 # SMTP server; empty = email disabled
+  port: 0                  # 0 = 587 (starttls), 465 (implicit), 25 (none)
+  tls: starttls            # starttls | implicit | none
+  username: ""
+  from: ""                 # e.g. "arp-notify <home@example.com>"
+  to: {}
+    # grandparents: ["grandma@example.com", "grandpa@example.com"]
+  subject: "arp-notify: {{.Text}}"
+  body: "{{.Text}}"
+# Publish every target's presence to MQTT as a retained "home" / "not_home"
+# state. The password is read from MQTT_PASSWORD in .env.
+mqtt:
+  broker: ""               # e.g. tcp://192.168.0.2:1883 or tls://...:8883; empty = disabled
+  client_id: arp-notify
+  username: ""
+  state_topic: "arp-notify/{{.ID}}/state"   # .ID = MAC without colons, .Name = target name
+  availability_topic: "arp-notify/status"   # "online"; "offline" as the last will
+  discovery: true          # Home Assistant MQTT discovery (device_tracker per target)
+  discovery_prefix: homeassistant
+rules:
+  allow_commands: false    # let rules in targets.yaml run programs on this host; only settable here
 `
 
 const targetsConfigTemplate = `# arp-notify monitoring targets.
+# Messages are Go templates over .Name, .Event, .Time, .IP, .AwayFor and
+# .Receiver, with the helpers clock, date, format, duration and greeting, e.g.
+# "{{greeting .Time}}, {{.Receiver}}! {{.Name}} is home."
 default_message: "Welcome home!"
 
+# Merge arrivals announced to the same receiver within window_sec into one
+# message (0 = off). The message is a Go template over .Names, .Messages and
+# .Count; join lists names as "Mom, Dad and Amy".
+batching:
+  window_sec: 0
+  message: "{{join .Names}} are home"
+
 # Reusable LINE user -> friendly name registry. Names are auto-filled from the
 # LINE profile when a user messages the bot; you can also edit them here.
 contacts: []
@@ -207,7 +232,128 @@ targets:
       mode: auto            # ip | broadcast | auto
       ip: "192.168.0.100"   # required for ip / auto
     message: ""             # optional; overrides default_message for this device
+    presence_policy:        # optional; per-target overrides, 0 = use config.yaml
+      absence_reset_min: 0
+      departure_delay_min: 0
+      miss_threshold: 0
+    quiet_hours:            # optional; hold back notifications in these windows
+      timezone: ""          # IANA name, e.g. Asia/Taipei; empty = server local time
+      action: suppress      # suppress | defer (send when the window ends)
+      windows: []
+        # - days: [mon, tue, wed, thu, fri]   # empty = every day
+        #   start: "22:00"
+        #   end: "07:00"                      # before start = wraps past midnight
+    expect:                 # optional; alert when the device isn't home when expected
+      timezone: ""
+      windows: []           # alert if the device isn't seen during a window
+        # - days: [mon, tue, wed, thu, fri]
+        #   start: "15:00"
+        #   end: "18:00"    # "home by 18:00 on school days"
+      away_alert_hours: 0   # alert once the device has been away this long; 0 = off
+      late_message: ""      # optional; default "<name> is not home yet (expected by HH:MM)."
+      away_message: ""      # optional; default "<name> has been away for N hours."
+      receivers: []         # optional; empty = this target's receivers
+    line_flex:              # optional; send LINE receivers a Flex card instead of plain text
+      enabled: false
+      color: ""             # header color #RRGGBB; empty = green for arrivals, red for departures
+      fields: []            # rows: time | away_for | ip | mac; empty = time, away_for
+      button_label: ""      # status page button (needs server.public_url); empty = "Open status"
     receivers:
       - id: "Uxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
         message: ""         # optional; overrides this device's message
+        # quiet_hours: {...} # optional; same format, applies to this receiver only
+
+# People group several targets (by MAC) so arrivals and departures are notified
+# once per person, to the receivers here; the targets' own receivers are not
+# notified while the person is enabled.
+people: []
+  # - name: "Dad"
+  #   enabled: true
+  #   devices: ["aa:bb:cc:dd:ee:ff"]
+  #   presence: any          # any | all: which devices must be present to count as home
+  #   message: ""            # optional arrival message; overrides default_message
+  #   departure_message: ""  # optional; departures are only notified when set
+  #   quiet_hours: {...}     # optional; same format as a target's; empty = its devices'
+  #   receivers:
+  #     - id: "Uxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
+
+# House-wide events across all enabled people and the targets that belong to
+# no person: the first one home, and the house becoming empty. Both messages are
+# templates; {{.Name}} lists who arrived. A receiver's message replaces
+# arrival_message.
+household:
+  enabled: false
+  arrival_message: "{{.Name}} is home."
+  empty_message: "Everyone has left."
+  # quiet_hours: {...}      # optional; same format as a target's
+  receivers: []
+
+# Alert when a MAC never seen on the LAN before shows up. The first scan after
+# enabling only learns the devices already present.
+new_devices:
+  enabled: false
+  message: "New device on the network"
+  allowlist: []             # MACs that never alert
+    # - mac: "aa:bb:cc:dd:ee:ff"
+    #   name: "Printer"
+  # quiet_hours: {...}      # optional; same format as a target's
+  receivers: []
+
+# Warn about ARP spoofing and IP conflicts seen in the broadcast scan: one IP
+# answering from several MACs, the gateway's MAC changing, or one MAC claiming
+# many IPs. Enabling it runs the broadcast scan every cycle.
+security:
+  enabled: false
+  gateway_ip: ""            # e.g. 192.168.0.1; empty = don't watch the gateway
+  max_ips_per_mac: 4        # IPs one MAC may answer for in a single scan
+  receivers: []
+
+# Admins are told when detection breaks: several failed or empty scan cycles in
+# a row (watchdog.failed_cycles in config.yaml), or no scan for 2x interval_sec.
+# A recovery notice follows once scans succeed again.
+admins: []
+  # - id: "Uxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
+
+# Delivery channels besides LINE. Receivers address a Discord webhook by name
+# as "discord:<name>" and get an embed with who, when and the IP. Outgoing
+# webhooks are addressed as "webhook:<name>"; the body is a Go template over
+# {{.Text}}, {{.Kind}}, {{.Subject}}, {{.Mac}}, {{.IP}}, {{.Time}} (empty = the
+# message as JSON). With secret_env set, requests are signed: see the README.
+channels:
+  discord:
+    webhooks: {}
+      # family: "https://discord.com/api/webhooks/<id>/<token>"
+  webhooks: {}
+    # presence:
+    #   url: "https://example.internal/hooks/presence"
+    #   method: POST
+    #   headers:
+    #     Content-Type: "application/json"
+    #   body: '{"who": {{json .Subject}}, "event": {{json .Kind}}, "text": {{json .Text}}}'
+    #   secret_env: PRESENCE_WEBHOOK_SECRET
+
+# Rules run actions on presence events. trigger: arrival | departure |
+# household_empty | household_occupied | unknown_device. subjects (target or
+# person names, MACs) and every "when" condition are optional. Actions: notify,
+# webhook (POSTs the event as JSON), command (no shell; needs rules.allow_commands
+# in config.yaml). Messages and command arguments are message templates:
+# {{.Name}}, {{.Event}} (the trigger), {{.Mac}}, {{.IP}}, {{.Time}}.
+rules: []
+  # - name: "porch light"
+  #   enabled: true
+  #   trigger: arrival
+  #   subjects: ["Amy"]
+  #   when:
+  #     timezone: "Asia/Taipei"
+  #     windows:
+  #       - { start: "18:00", end: "06:00" }
+  #     days: ["mon", "tue", "wed", "thu", "fri"]
+  #     absent: ["Dad"]
+  #   actions:
+  #     - type: webhook
+  #       url: "http://homeassistant.local:8123/api/webhook/porch-light"
+  #     - type: notify
+  #       receivers:
+  #         - id: "Uxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
+  #       message: "{{.Name}} is home."
 `
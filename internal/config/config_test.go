@@ -37,6 +37,15 @@ func TestApplySystemDefaults(t *testing.T) {
 	if cfg.Monitor.AbsenceResetMin != 1440 {
 		t.Errorf("AbsenceResetMin = %d, want 1440", cfg.Monitor.AbsenceResetMin)
 	}
+	if cfg.Monitor.DepartureDelayMin != 10 {
+		t.Errorf("DepartureDelayMin = %d, want 10", cfg.Monitor.DepartureDelayMin)
+	}
+	if cfg.Monitor.MissThreshold != 3 {
+		t.Errorf("MissThreshold = %d, want 3", cfg.Monitor.MissThreshold)
+	}
+	if cfg.Watchdog.FailedCycles != 3 {
+		t.Errorf("FailedCycles = %d, want 3", cfg.Watchdog.FailedCycles)
+	}
 	if cfg.Server.Host != "127.0.0.1" {
 		t.Errorf("Host = %q, want 127.0.0.1", cfg.Server.Host)
 	}
@@ -74,8 +83,9 @@ func validSystemConfig(bin string) SystemConfig {
 			BroadcastTimeoutSec:  15,
 			IndividualTimeoutSec: 2,
 		},
-		Monitor: MonitorConfig{AbsenceResetMin: 1440},
-		Server:  ServerConfig{Host: "127.0.0.1", Port: 5000},
+		Monitor:  MonitorConfig{AbsenceResetMin: 1440, DepartureDelayMin: 10, MissThreshold: 3},
+		Watchdog: WatchdogConfig{FailedCycles: 3},
+		Server:   ServerConfig{Host: "127.0.0.1", Port: 5000},
 	}
 }
 
@@ -96,10 +106,21 @@ func TestValidateSystemConfig(t *testing.T) {
 		{"zero broadcast timeout", func(c *SystemConfig) { c.ArpScan.BroadcastTimeoutSec = 0 }},
 		{"zero individual timeout", func(c *SystemConfig) { c.ArpScan.IndividualTimeoutSec = 0 }},
 		{"zero absence", func(c *SystemConfig) { c.Monitor.AbsenceResetMin = 0 }},
+		{"zero departure delay", func(c *SystemConfig) { c.Monitor.DepartureDelayMin = 0 }},
+		{"zero miss threshold", func(c *SystemConfig) { c.Monitor.MissThreshold = 0 }},
+		{"zero watchdog failed cycles", func(c *SystemConfig) { c.Watchdog.FailedCycles = 0 }},
 		{"bad host", func(c *SystemConfig) { c.Server.Host = "not-an-ip" }},
 		{"empty host", func(c *SystemConfig) { c.Server.Host = "" }},
 		{"port zero", func(c *SystemConfig) { c.Server.Port = 0 }},
 		{"port too large", func(c *SystemConfig) { c.Server.Port = 70000 }},
+		{"bad public url", func(c *SystemConfig) { c.Server.PublicURL = "home.example.com" }},
+		{"bad email tls", func(c *SystemConfig) { c.Email = EmailConfig{Host: "smtp", From: "a@example.com", TLS: "ssl"} }},
+		{"bad email from", func(c *SystemConfig) { c.Email = EmailConfig{Host: "smtp", From: "nobody"} }},
+		{"empty email list", func(c *SystemConfig) {
+			c.Email = EmailConfig{Host: "smtp", From: "a@example.com", To: map[string][]string{"family": nil}}
+		}},
+		{"bad email subject", func(c *SystemConfig) { c.Email = EmailConfig{Host: "smtp", From: "a@example.com", Subject: "{{"} }},
+		{"unknown email body field", func(c *SystemConfig) { c.Email = EmailConfig{Host: "smtp", From: "a@example.com", Body: "{{.Nmae}}"} }},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -127,4 +148,18 @@ func TestValidateIface(t *testing.T) {
 	}
 }
 
+func TestMonitorConfigForTarget(t *testing.T) {
+	global := MonitorConfig{AbsenceResetMin: 1440, DepartureDelayMin: 10, MissThreshold: 3}
+
+	if got := global.For(Target{}); got != global {
+		t.Errorf("target without overrides should get the global values, got %+v", got)
+	}
+
+	got := global.For(Target{Policy: PresencePolicy{DepartureDelayMin: 120, MissThreshold: 1}})
+	want := MonitorConfig{AbsenceResetMin: 1440, DepartureDelayMin: 120, MissThreshold: 1}
+	if got != want {
+		t.Errorf("For() = %+v, want %+v", got, want)
+	}
+}
+
 func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/mail"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// SMTP connection security.
+const (
+	EmailTLSStartTLS = "starttls" // plain connection upgraded with STARTTLS (default)
+	EmailTLSImplicit = "implicit" // TLS from the first byte, usually port 465
+	EmailTLSNone     = "none"     // unencrypted, for a local relay only
+)
+
+// Default email templates, rendered with the notifier message.
+const (
+	DefaultEmailSubject = "arp-notify: {{.Text}}"
+	DefaultEmailBody    = "{{.Text}}\n"
+)
+
+// EmailConfig sets up the SMTP channel. Receivers address either a named
+// recipient list from To ("email:grandparents") or a single address
+// ("email:grandma@example.com"). The password comes from SMTP_PASSWORD in the
+// environment, like the bot tokens.
+type EmailConfig struct {
+	Host     string              `yaml:"host,omitempty" json:"host"` // empty = email disabled
+	Port     int                 `yaml:"port,omitempty" json:"port"` // 0 = 587, or 465 for implicit TLS, 25 for none
+	TLS      string              `yaml:"tls,omitempty" json:"tls"`   // starttls | implicit | none
+	Username string              `yaml:"username,omitempty" json:"username"`
+	From     string              `yaml:"from,omitempty" json:"from"`
+	To       map[string][]string `yaml:"to,omitempty" json:"to"`           // list name -> addresses
+	Subject  string              `yaml:"subject,omitempty" json:"subject"` // text/template; empty = DefaultEmailSubject
+	Body     string              `yaml:"body,omitempty" json:"body"`       // text/template; empty = DefaultEmailBody
+}
+
+var emailTLSModes = []string{EmailTLSStartTLS, EmailTLSImplicit, EmailTLSNone}
+
+// Security returns the connection security, STARTTLS by default.
+func (e EmailConfig) Security() string {
+	if e.TLS == "" {
+		return EmailTLSStartTLS
+	}
+	return strings.ToLower(e.TLS)
+}
+
+// ServerPort returns the SMTP port, defaulting by connection security.
+func (e EmailConfig) ServerPort() int {
+	switch {
+	case e.Port != 0:
+		return e.Port
+	case e.Security() == EmailTLSImplicit:
+		return 465
+	case e.Security() == EmailTLSNone:
+		return 25
+	}
+	return 587
+}
+
+// Recipients resolves a receiver address: a list name from To, or an email
+// address.
+func (e EmailConfig) Recipients(address string) ([]string, error) {
+	if list, ok := e.To[address]; ok {
+		return list, nil
+	}
+	if strings.Contains(address, "@") {
+		return []string{address}, nil
+	}
+	return nil, fmt.Errorf("no email recipient list named %q", address)
+}
+
+// Templates parses the subject and body templates.
+func (e EmailConfig) Templates() (subject, body *template.Template, err error) {
+	s, b := e.Subject, e.Body
+	if s == "" {
+		s = DefaultEmailSubject
+	}
+	if b == "" {
+		b = DefaultEmailBody
+	}
+	if subject, err = template.New("subject").Parse(s); err != nil {
+		return nil, nil, fmt.Errorf("subject: %w", err)
+	}
+	if body, err = template.New("body").Parse(b); err != nil {
+		return nil, nil, fmt.Errorf("body: %w", err)
+	}
+	return subject, body, nil
+}
+
+func validateEmail(e EmailConfig) error {
+	if e.Host == "" {
+		return nil
+	}
+	if !slices.Contains(emailTLSModes, e.Security()) {
+		return fmt.Errorf("email.tls must be one of %s", strings.Join(emailTLSModes, ", "))
+	}
+	if e.Port < 0 || e.Port > 65535 {
+		return fmt.Errorf("email.port must be between 1 and 65535")
+	}
+	if _, err := mail.ParseAddress(e.From); err != nil {
+		return fmt.Errorf("email.from: %w", err)
+	}
+	for name, list := range e.To {
+		if len(list) == 0 {
+			return fmt.Errorf("email.to: %q has no addresses", name)
+		}
+		for _, addr := range list {
+			if _, err := mail.ParseAddress(addr); err != nil {
+				return fmt.Errorf("email.to: %q: %w", name, err)
+			}
+		}
+	}
+	subject, body, err := e.Templates()
+	if err == nil {
+		if err = subject.Execute(io.Discard, sampleNotification); err != nil {
+			err = fmt.Errorf("subject: %w", err)
+		} else if err = body.Execute(io.Discard, sampleNotification); err != nil {
+			err = fmt.Errorf("body: %w", err)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("email: invalid %w", err)
+	}
+	return nil
+}
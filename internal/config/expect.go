@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Expectation declares when a target is expected home. Each window is an
+// expected-arrival window: if the device isn't seen between its start and end
+// (e.g. "mon..fri 15:00-18:00" for "home by 18:00 on school days"), an alert
+// goes out when it closes. AwayAlertHours additionally alerts once the device
+// has been away that long.
+type Expectation struct {
+	Timezone       string       `yaml:"timezone,omitempty" json:"timezone"`
+	Windows        []TimeWindow `yaml:"windows,omitempty" json:"windows"`
+	AwayAlertHours int          `yaml:"away_alert_hours,omitempty" json:"away_alert_hours"`
+	LateMessage    string       `yaml:"late_message,omitempty" json:"late_message"`
+	AwayMessage    string       `yaml:"away_message,omitempty" json:"away_message"`
+	Receivers      []Receiver   `yaml:"receivers,omitempty" json:"receivers"` // empty = the target's receivers
+}
+
+// Location returns the expectation's timezone, defaulting to the local zone.
+func (e Expectation) Location() *time.Location {
+	return Schedule{Timezone: e.Timezone}.Location()
+}
+
+// ReceiversFor returns who gets the target's expectation alerts.
+func (e Expectation) ReceiversFor(t Target) []Receiver {
+	if len(e.Receivers) > 0 {
+		return e.Receivers
+	}
+	return t.Receivers
+}
+
+// Occurrence is one concrete occurrence of a time window.
+type Occurrence struct {
+	Start, End time.Time
+}
+
+// ClosedBetween returns the window occurrences that ended in (after, until].
+func (e Expectation) ClosedBetween(after, until time.Time) []Occurrence {
+	var out []Occurrence
+	for _, w := range e.Windows {
+		for start, end := range w.occurrences(until.In(e.Location())) {
+			if end.After(after) && !end.After(until) {
+				out = append(out, Occurrence{Start: start, End: end})
+			}
+		}
+	}
+	return out
+}
+
+func validateExpectation(e Expectation) error {
+	if err := validateSchedule(Schedule{Timezone: e.Timezone, Windows: e.Windows}); err != nil {
+		return err
+	}
+	if e.AwayAlertHours < 0 {
+		return fmt.Errorf("away_alert_hours must be >= 0")
+	}
+	for j, r := range e.Receivers {
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("receiver #%d: %w", j+1, err)
+		}
+		if err := validateSchedule(r.QuietHours); err != nil {
+			return fmt.Errorf("receiver #%d quiet_hours: %w", j+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rows a LINE Flex notification can show below the message.
+const (
+	FlexFieldTime    = "time"
+	FlexFieldAwayFor = "away_for"
+	FlexFieldIP      = "ip"
+	FlexFieldMac     = "mac"
+)
+
+var flexFields = []string{FlexFieldTime, FlexFieldAwayFor, FlexFieldIP, FlexFieldMac}
+
+// LineFlex sends a target's or person's arrivals and departures to LINE
+// receivers as a Flex Message card instead of plain text. The rendered message
+// stays the card's body and its altText.
+type LineFlex struct {
+	Enabled     bool     `yaml:"enabled" json:"enabled"`
+	Color       string   `yaml:"color,omitempty" json:"color"`               // header color, #RRGGBB; empty = by event
+	Fields      []string `yaml:"fields,omitempty" json:"fields"`             // rows to show, in order; empty = time, away_for
+	ButtonLabel string   `yaml:"button_label,omitempty" json:"button_label"` // status page button; empty = "Open status"
+}
+
+// Rows returns the rows to show.
+func (f LineFlex) Rows() []string {
+	if len(f.Fields) == 0 {
+		return []string{FlexFieldTime, FlexFieldAwayFor}
+	}
+	return f.Fields
+}
+
+var colorRegex = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+func validateLineFlex(f LineFlex) error {
+	if f.Color != "" && !colorRegex.MatchString(f.Color) {
+		return fmt.Errorf("invalid color %q (expected #RRGGBB)", f.Color)
+	}
+	for _, field := range f.Fields {
+		if !slices.Contains(flexFields, field) {
+			return fmt.Errorf("unknown field %q (expected %s)", field, strings.Join(flexFields, "|"))
+		}
+	}
+	if utf8.RuneCountInString(f.ButtonLabel) > 40 {
+		return fmt.Errorf("button_label is longer than LINE's 40 characters")
+	}
+	return nil
+}
+
+func validatePublicURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("server.public_url %q is not an http(s) URL", raw)
+	}
+	return nil
+}
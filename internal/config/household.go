@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Household configures house-wide occupancy events: the first member arriving
+// at an empty house and the last member leaving it. Members are all enabled
+// people plus every enabled target that does not belong to a person. The
+// messages are templates; {{.Name}} lists who arrived (empty when the house
+// empties) and {{.Event}} is household_occupied or household_empty.
+type Household struct {
+	Enabled        bool       `yaml:"enabled" json:"enabled"`
+	ArrivalMessage string     `yaml:"arrival_message,omitempty" json:"arrival_message"`
+	EmptyMessage   string     `yaml:"empty_message,omitempty" json:"empty_message"`
+	QuietHours     Schedule   `yaml:"quiet_hours,omitempty" json:"quiet_hours"`
+	Receivers      []Receiver `yaml:"receivers" json:"receivers"`
+}
+
+// MessageFor resolves the message template a given receiver should get for
+// the house becoming occupied (or empty), or "" when that event is off. A
+// receiver's message replaces the arrival message, as for targets and people;
+// the empty message is the same for everyone.
+func (h Household) MessageFor(r Receiver, occupied bool) string {
+	if !occupied {
+		return h.EmptyMessage
+	}
+	if h.ArrivalMessage != "" && r.Message != "" {
+		return r.Message
+	}
+	return h.ArrivalMessage
+}
+
+func validateHousehold(h Household) error {
+	if !h.Enabled {
+		return nil
+	}
+	if h.ArrivalMessage == "" && h.EmptyMessage == "" {
+		return errors.New("household: at least one of arrival_message or empty_message is required")
+	}
+	if err := validateMessage(h.ArrivalMessage); err != nil {
+		return fmt.Errorf("household: arrival_message: %w", err)
+	}
+	if err := validateMessage(h.EmptyMessage); err != nil {
+		return fmt.Errorf("household: empty_message: %w", err)
+	}
+	if err := validateSchedule(h.QuietHours); err != nil {
+		return fmt.Errorf("household: quiet_hours: %w", err)
+	}
+	for j, r := range h.Receivers {
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("household: receiver #%d: %w", j+1, err)
+		}
+		if err := validateMessage(r.Message); err != nil {
+			return fmt.Errorf("household: receiver #%d message: %w", j+1, err)
+		}
+		if err := validateSchedule(r.QuietHours); err != nil {
+			return fmt.Errorf("household: receiver #%d quiet_hours: %w", j+1, err)
+		}
+	}
+	return nil
+}
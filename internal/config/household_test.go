@@ -0,0 +1,51 @@
+package config
+
+import "testing"
+
+func TestValidateHousehold(t *testing.T) {
+	if err := validateHousehold(Household{}); err != nil {
+		t.Errorf("disabled household should not be validated: %v", err)
+	}
+
+	valid := Household{Enabled: true, EmptyMessage: "Everyone left", Receivers: []Receiver{{ID: "U1"}}}
+	if err := validateHousehold(valid); err != nil {
+		t.Errorf("valid household rejected: %v", err)
+	}
+
+	noMessage := Household{Enabled: true, Receivers: []Receiver{{ID: "U1"}}}
+	if err := validateHousehold(noMessage); err == nil {
+		t.Error("expected error for household without messages")
+	}
+
+	emptyID := Household{Enabled: true, ArrivalMessage: "Hi", Receivers: []Receiver{{ID: ""}}}
+	if err := validateHousehold(emptyID); err == nil {
+		t.Error("expected error for empty receiver id")
+	}
+
+	badTemplate := Household{Enabled: true, ArrivalMessage: "{{.Nmae}} is home", Receivers: []Receiver{{ID: "U1"}}}
+	if err := validateHousehold(badTemplate); err == nil {
+		t.Error("expected error for an invalid arrival_message template")
+	}
+
+	badReceiver := Household{Enabled: true, EmptyMessage: "Bye", Receivers: []Receiver{{ID: "U1", Message: "{{"}}}
+	if err := validateHousehold(badReceiver); err == nil {
+		t.Error("expected error for an invalid receiver message")
+	}
+}
+
+func TestHouseholdMessageFor(t *testing.T) {
+	h := Household{ArrivalMessage: "Someone is home", EmptyMessage: "Everyone left"}
+	own := Receiver{ID: "U1", Message: "Hi {{.Name}}"}
+	if got := h.MessageFor(own, true); got != own.Message {
+		t.Errorf("arrival for a receiver with a message = %q", got)
+	}
+	if got := h.MessageFor(Receiver{ID: "U2"}, true); got != h.ArrivalMessage {
+		t.Errorf("arrival = %q", got)
+	}
+	if got := h.MessageFor(own, false); got != h.EmptyMessage {
+		t.Errorf("empty = %q", got)
+	}
+	if got := (Household{EmptyMessage: "Bye"}).MessageFor(own, true); got != "" {
+		t.Errorf("arrival with the event off = %q, want empty", got)
+	}
+}
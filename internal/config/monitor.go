@@ -15,9 +15,17 @@ const (
 
 // TargetsConfig holds the monitoring targets loaded from targets.yaml.
 type TargetsConfig struct {
-	DefaultMessage string    `yaml:"default_message" json:"default_message"`
-	Contacts       []Contact `yaml:"contacts" json:"contacts"`
-	Targets        []Target  `yaml:"targets" json:"targets"`
+	DefaultMessage string         `yaml:"default_message" json:"default_message"`
+	Contacts       []Contact      `yaml:"contacts" json:"contacts"`
+	Targets        []Target       `yaml:"targets" json:"targets"`
+	People         []Person       `yaml:"people,omitempty" json:"people"`
+	Household      Household      `yaml:"household" json:"household"`
+	NewDevices     NewDevices     `yaml:"new_devices" json:"new_devices"`
+	Security       Security       `yaml:"security" json:"security"`
+	Admins         []Receiver     `yaml:"admins,omitempty" json:"admins"` // told when detection breaks
+	Rules          []Rule         `yaml:"rules,omitempty" json:"rules"`
+	Batching       Batching       `yaml:"batching,omitempty" json:"batching"` // merge arrivals announced together
+	Channels       ChannelsConfig `yaml:"channels,omitempty" json:"channels"` // delivery channels besides LINE
 }
 
 // Contact maps a LINE user ID to a human-friendly name, reusable across targets.
@@ -27,12 +35,24 @@ type Contact struct {
 }
 
 type Target struct {
-	Name      string     `yaml:"name" json:"name"`
-	Mac       string     `yaml:"mac" json:"mac"`
-	Enabled   bool       `yaml:"enabled" json:"enabled"`
-	Detection Detection  `yaml:"detection" json:"detection"`
-	Message   string     `yaml:"message,omitempty" json:"message"`
-	Receivers []Receiver `yaml:"receivers" json:"receivers"`
+	Name       string         `yaml:"name" json:"name"`
+	Mac        string         `yaml:"mac" json:"mac"`
+	Enabled    bool           `yaml:"enabled" json:"enabled"`
+	Detection  Detection      `yaml:"detection" json:"detection"`
+	Message    string         `yaml:"message,omitempty" json:"message"`
+	QuietHours Schedule       `yaml:"quiet_hours,omitempty" json:"quiet_hours"`
+	Policy     PresencePolicy `yaml:"presence_policy,omitempty" json:"presence_policy"`
+	Expect     Expectation    `yaml:"expect,omitempty" json:"expect"`
+	LineFlex   LineFlex       `yaml:"line_flex,omitempty" json:"line_flex"` // rich LINE notifications
+	Receivers  []Receiver     `yaml:"receivers" json:"receivers"`
+}
+
+// PresencePolicy overrides the global monitor settings for one target. Zero
+// fields fall back to the values in config.yaml.
+type PresencePolicy struct {
+	AbsenceResetMin   int `yaml:"absence_reset_min,omitempty" json:"absence_reset_min"`
+	DepartureDelayMin int `yaml:"departure_delay_min,omitempty" json:"departure_delay_min"`
+	MissThreshold     int `yaml:"miss_threshold,omitempty" json:"miss_threshold"`
 }
 
 type Detection struct {
@@ -41,12 +61,14 @@ type Detection struct {
 }
 
 type Receiver struct {
-	ID      string `yaml:"id" json:"id"`
-	Message string `yaml:"message,omitempty" json:"message"`
+	ID         string   `yaml:"id" json:"id"`
+	Message    string   `yaml:"message,omitempty" json:"message"`
+	QuietHours Schedule `yaml:"quiet_hours,omitempty" json:"quiet_hours"`
 }
 
-// MessageFor resolves the message a given receiver should get, applying the
-// precedence: receiver.Message -> target.Message -> defaultMessage.
+// MessageFor resolves the message template a given receiver should get,
+// applying the precedence: receiver.Message -> target.Message ->
+// defaultMessage. Render it with RenderMessage.
 func (t Target) MessageFor(r Receiver, defaultMessage string) string {
 	if r.Message != "" {
 		return r.Message
@@ -60,6 +82,9 @@ func (t Target) MessageFor(r Receiver, defaultMessage string) string {
 var macRegex = regexp.MustCompile(`^([0-9A-Fa-f]{2}:){5}[0-9A-Fa-f]{2}$`)
 
 func validateTargetsConfig(cfg *TargetsConfig) error {
+	if err := validateMessage(cfg.DefaultMessage); err != nil {
+		return fmt.Errorf("default_message: %w", err)
+	}
 	for i, t := range cfg.Targets {
 		label := t.Name
 		if label == "" {
@@ -89,9 +114,32 @@ func validateTargetsConfig(cfg *TargetsConfig) error {
 			return fmt.Errorf("target %s: invalid detection mode %q (expected ip|broadcast|auto)", label, t.Detection.Mode)
 		}
 
+		if t.Policy.AbsenceResetMin < 0 || t.Policy.DepartureDelayMin < 0 || t.Policy.MissThreshold < 0 {
+			return fmt.Errorf("target %s: presence_policy values must be >= 0 (0 = use the global value)", label)
+		}
+
+		if err := validateMessage(t.Message); err != nil {
+			return fmt.Errorf("target %s: message: %w", label, err)
+		}
+		if err := validateSchedule(t.QuietHours); err != nil {
+			return fmt.Errorf("target %s: quiet_hours: %w", label, err)
+		}
+		if err := validateExpectation(t.Expect); err != nil {
+			return fmt.Errorf("target %s: expect: %w", label, err)
+		}
+		if err := validateLineFlex(t.LineFlex); err != nil {
+			return fmt.Errorf("target %s: line_flex: %w", label, err)
+		}
+
 		for j, r := range t.Receivers {
-			if r.ID == "" {
-				return fmt.Errorf("target %s: receiver #%d has an empty id", label, j+1)
+			if err := r.validate(); err != nil {
+				return fmt.Errorf("target %s: receiver #%d: %w", label, j+1, err)
+			}
+			if err := validateMessage(r.Message); err != nil {
+				return fmt.Errorf("target %s: receiver #%d message: %w", label, j+1, err)
+			}
+			if err := validateSchedule(r.QuietHours); err != nil {
+				return fmt.Errorf("target %s: receiver #%d quiet_hours: %w", label, j+1, err)
 			}
 		}
 	}
@@ -102,5 +150,38 @@ func validateTargetsConfig(cfg *TargetsConfig) error {
 		}
 	}
 
+	if err := validatePeople(cfg); err != nil {
+		return err
+	}
+	if err := validateHousehold(cfg.Household); err != nil {
+		return err
+	}
+	if err := validateNewDevices(cfg.NewDevices); err != nil {
+		return err
+	}
+	if err := validateSecurity(cfg.Security); err != nil {
+		return err
+	}
+	if err := validateAdmins(cfg.Admins); err != nil {
+		return err
+	}
+	if err := validateBatching(cfg.Batching); err != nil {
+		return err
+	}
+	if err := validateRules(cfg); err != nil {
+		return err
+	}
+	return validateChannels(cfg)
+}
+
+func validateAdmins(admins []Receiver) error {
+	for j, r := range admins {
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("admins: receiver #%d: %w", j+1, err)
+		}
+		if err := validateSchedule(r.QuietHours); err != nil {
+			return fmt.Errorf("admins: receiver #%d quiet_hours: %w", j+1, err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// Defaults for the MQTT publisher.
+const (
+	DefaultMQTTClientID        = "arp-notify"
+	DefaultMQTTStateTopic      = "arp-notify/{{.ID}}/state"
+	DefaultMQTTAvailability    = "arp-notify/status"
+	DefaultMQTTDiscoveryPrefix = "homeassistant"
+)
+
+// MQTTConfig publishes every target's presence to an MQTT broker, as retained
+// "home"/"not_home" states, with Home Assistant discovery. The password comes
+// from MQTT_PASSWORD in the environment.
+type MQTTConfig struct {
+	Broker            string `yaml:"broker,omitempty" json:"broker"` // tcp://host:1883 or tls://host:8883; empty = disabled
+	ClientID          string `yaml:"client_id,omitempty" json:"client_id"`
+	Username          string `yaml:"username,omitempty" json:"username"`
+	StateTopic        string `yaml:"state_topic,omitempty" json:"state_topic"`               // text/template over .ID and .Name
+	AvailabilityTopic string `yaml:"availability_topic,omitempty" json:"availability_topic"` // "online", or "offline" as the last will
+	Discovery         bool   `yaml:"discovery" json:"discovery"`                             // publish Home Assistant discovery configs
+	DiscoveryPrefix   string `yaml:"discovery_prefix,omitempty" json:"discovery_prefix"`
+}
+
+// Enabled reports whether a broker is configured.
+func (m MQTTConfig) Enabled() bool {
+	return m.Broker != ""
+}
+
+// WithDefaults fills in the unset optional fields.
+func (m MQTTConfig) WithDefaults() MQTTConfig {
+	if m.ClientID == "" {
+		m.ClientID = DefaultMQTTClientID
+	}
+	if m.StateTopic == "" {
+		m.StateTopic = DefaultMQTTStateTopic
+	}
+	if m.AvailabilityTopic == "" {
+		m.AvailabilityTopic = DefaultMQTTAvailability
+	}
+	if m.DiscoveryPrefix == "" {
+		m.DiscoveryPrefix = DefaultMQTTDiscoveryPrefix
+	}
+	return m
+}
+
+// StateTopicTemplate parses the state topic.
+func (m MQTTConfig) StateTopicTemplate() (*template.Template, error) {
+	return template.New("state_topic").Parse(m.WithDefaults().StateTopic)
+}
+
+func validateMQTT(m MQTTConfig) error {
+	if !m.Enabled() {
+		return nil
+	}
+	u, err := url.Parse(m.Broker)
+	if err != nil || u.Host == "" || (u.Scheme != "tcp" && u.Scheme != "mqtt" && u.Scheme != "tls" && u.Scheme != "mqtts") {
+		return fmt.Errorf("mqtt.broker %q: want tcp://host:port or tls://host:port", m.Broker)
+	}
+	if _, err := m.StateTopicTemplate(); err != nil {
+		return fmt.Errorf("mqtt.state_topic: %w", err)
+	}
+	for field, topic := range map[string]string{"availability_topic": m.AvailabilityTopic, "discovery_prefix": m.DiscoveryPrefix} {
+		if strings.ContainsAny(topic, "+#") {
+			return fmt.Errorf("mqtt.%s must not contain wildcards", field)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NewDevices configures alerts for MAC addresses never seen on the LAN before.
+// Detection needs the broadcast scan, so enabling it runs one every cycle.
+type NewDevices struct {
+	Enabled    bool          `yaml:"enabled" json:"enabled"`
+	Message    string        `yaml:"message,omitempty" json:"message"`
+	Allowlist  []KnownDevice `yaml:"allowlist" json:"allowlist"`
+	QuietHours Schedule      `yaml:"quiet_hours,omitempty" json:"quiet_hours"`
+	Receivers  []Receiver    `yaml:"receivers" json:"receivers"`
+}
+
+// KnownDevice is an allowlisted MAC that never triggers a new-device alert.
+type KnownDevice struct {
+	Mac  string `yaml:"mac" json:"mac"`
+	Name string `yaml:"name,omitempty" json:"name"`
+}
+
+// Allows reports whether the MAC is on the allowlist.
+func (n NewDevices) Allows(mac string) bool {
+	for _, k := range n.Allowlist {
+		if strings.EqualFold(k.Mac, mac) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateNewDevices(n NewDevices) error {
+	for i, k := range n.Allowlist {
+		if !macRegex.MatchString(k.Mac) {
+			return fmt.Errorf("new_devices: allowlist #%d: invalid mac %q (expected aa:bb:cc:dd:ee:ff)", i+1, k.Mac)
+		}
+	}
+	if err := validateSchedule(n.QuietHours); err != nil {
+		return fmt.Errorf("new_devices: quiet_hours: %w", err)
+	}
+	for j, r := range n.Receivers {
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("new_devices: receiver #%d: %w", j+1, err)
+		}
+		if err := validateSchedule(r.QuietHours); err != nil {
+			return fmt.Errorf("new_devices: receiver #%d quiet_hours: %w", j+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import "time"
+
+// Notification is one notification on its way to a receiver (notifier.Message).
+// Text is always set; the other fields describe what it is about, for channels
+// that show more than text (e.g. Discord embeds), and may be empty.
+// Notifications are persisted in the outbox, hence the JSON tags. Webhook
+// bodies and email subjects and bodies are rendered with it.
+type Notification struct {
+	Text    string        `json:"text"`
+	Kind    string        `json:"kind,omitempty"`    // e.g. arrival, departure; empty for plain messages
+	Subject string        `json:"subject,omitempty"` // target or person name
+	Mac     string        `json:"mac,omitempty"`
+	IP      string        `json:"ip,omitempty"`
+	Time    time.Time     `json:"time"`
+	AwayFor time.Duration `json:"awayFor,omitempty"` // for an arrival, how long the subject was away; 0 if unknown
+
+	LineFlex *LineFlex `json:"lineFlex,omitempty"` // LINE layout for the subject; nil = plain text
+
+	// ID identifies the notification across delivery attempts, so a channel
+	// that supports it can drop duplicates (LINE's X-Line-Retry-Key, which
+	// must be a UUID). Empty for one-off messages.
+	ID string `json:"id,omitempty"`
+}
+
+// sampleNotification is what webhook and email templates are test-rendered
+// with when they are validated.
+var sampleNotification = Notification{
+	Text:    "Amy is home",
+	Kind:    sampleMessageData.Event,
+	Subject: sampleMessageData.Name,
+	Mac:     sampleMessageData.Mac,
+	IP:      sampleMessageData.IP,
+	Time:    sampleMessageData.Time,
+	AwayFor: sampleMessageData.AwayFor,
+	ID:      "00000000-0000-4000-8000-000000000000",
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Presence rules for people.
+const (
+	PresenceAny = "any" // home while any of the person's devices is present
+	PresenceAll = "all" // home only while all of the person's devices are present
+)
+
+// Person groups several devices (targets, referenced by MAC) under one
+// identity, so arrivals and departures are notified once per person instead of
+// once per device.
+type Person struct {
+	Name             string     `yaml:"name" json:"name"`
+	Enabled          bool       `yaml:"enabled" json:"enabled"`
+	Devices          []string   `yaml:"devices" json:"devices"`
+	Presence         string     `yaml:"presence,omitempty" json:"presence"`
+	Message          string     `yaml:"message,omitempty" json:"message"`
+	DepartureMessage string     `yaml:"departure_message,omitempty" json:"departure_message"`
+	LineFlex         LineFlex   `yaml:"line_flex,omitempty" json:"line_flex"`     // rich LINE notifications
+	QuietHours       Schedule   `yaml:"quiet_hours,omitempty" json:"quiet_hours"` // empty = the devices'
+	Receivers        []Receiver `yaml:"receivers" json:"receivers"`
+}
+
+// MessageFor resolves the arrival message template a given receiver should
+// get, applying the precedence: receiver.Message -> person.Message ->
+// defaultMessage.
+func (p Person) MessageFor(r Receiver, defaultMessage string) string {
+	if r.Message != "" {
+		return r.Message
+	}
+	if p.Message != "" {
+		return p.Message
+	}
+	return defaultMessage
+}
+
+// RequiresAll reports whether every device must be present for the person to
+// count as home. An empty presence rule means "any".
+func (p Person) RequiresAll() bool {
+	return p.Presence == PresenceAll
+}
+
+// QuietHoursFor returns the person's quiet hours: their own, or else those of
+// the first of their devices that has any.
+func (p Person) QuietHoursFor(targets []Target) Schedule {
+	if len(p.QuietHours.Windows) > 0 {
+		return p.QuietHours
+	}
+	for _, d := range p.Devices {
+		for _, t := range targets {
+			if strings.EqualFold(t.Mac, d) && len(t.QuietHours.Windows) > 0 {
+				return t.QuietHours
+			}
+		}
+	}
+	return Schedule{}
+}
+
+// OwnsMac reports whether the MAC is one of the person's devices.
+func (p Person) OwnsMac(mac string) bool {
+	for _, d := range p.Devices {
+		if strings.EqualFold(d, mac) {
+			return true
+		}
+	}
+	return false
+}
+
+// PersonOf returns the enabled person the MAC belongs to, if any.
+func (cfg TargetsConfig) PersonOf(mac string) (Person, bool) {
+	for _, p := range cfg.People {
+		if p.Enabled && p.OwnsMac(mac) {
+			return p, true
+		}
+	}
+	return Person{}, false
+}
+
+func validatePeople(cfg *TargetsConfig) error {
+	targetMacs := make(map[string]bool, len(cfg.Targets))
+	for _, t := range cfg.Targets {
+		targetMacs[strings.ToLower(t.Mac)] = true
+	}
+
+	names := make(map[string]bool, len(cfg.People))
+	owner := make(map[string]string) // mac -> person name
+	for i, p := range cfg.People {
+		if p.Name == "" {
+			return fmt.Errorf("person #%d: name is required", i+1)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("person %s: duplicate name", p.Name)
+		}
+		names[p.Name] = true
+
+		switch p.Presence {
+		case "", PresenceAny, PresenceAll:
+		default:
+			return fmt.Errorf("person %s: invalid presence %q (expected any|all)", p.Name, p.Presence)
+		}
+
+		if len(p.Devices) == 0 {
+			return fmt.Errorf("person %s: at least one device is required", p.Name)
+		}
+		for _, d := range p.Devices {
+			mac := strings.ToLower(d)
+			if !targetMacs[mac] {
+				return fmt.Errorf("person %s: device %q is not a configured target", p.Name, d)
+			}
+			if other, ok := owner[mac]; ok {
+				return fmt.Errorf("person %s: device %q already belongs to %s", p.Name, d, other)
+			}
+			owner[mac] = p.Name
+		}
+
+		if err := validateLineFlex(p.LineFlex); err != nil {
+			return fmt.Errorf("person %s: line_flex: %w", p.Name, err)
+		}
+		if err := validateMessage(p.Message); err != nil {
+			return fmt.Errorf("person %s: message: %w", p.Name, err)
+		}
+		if err := validateMessage(p.DepartureMessage); err != nil {
+			return fmt.Errorf("person %s: departure_message: %w", p.Name, err)
+		}
+		if err := validateSchedule(p.QuietHours); err != nil {
+			return fmt.Errorf("person %s: quiet_hours: %w", p.Name, err)
+		}
+		for j, r := range p.Receivers {
+			if err := r.validate(); err != nil {
+				return fmt.Errorf("person %s: receiver #%d: %w", p.Name, j+1, err)
+			}
+			if err := validateMessage(r.Message); err != nil {
+				return fmt.Errorf("person %s: receiver #%d message: %w", p.Name, j+1, err)
+			}
+			if err := validateSchedule(r.QuietHours); err != nil {
+				return fmt.Errorf("person %s: receiver #%d quiet_hours: %w", p.Name, j+1, err)
+			}
+		}
+	}
+	return nil
+}
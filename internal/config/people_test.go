@@ -0,0 +1,81 @@
+package config
+
+import "testing"
+
+func validPerson() Person {
+	return Person{
+		Name:      "Dad",
+		Enabled:   true,
+		Devices:   []string{"aa:bb:cc:dd:ee:ff"},
+		Receivers: []Receiver{{ID: "U123"}},
+	}
+}
+
+func TestValidatePeopleValid(t *testing.T) {
+	p := validPerson()
+	p.Devices = []string{"AA:BB:CC:DD:EE:FF"} // matching is case-insensitive
+	p.Presence = PresenceAll
+	cfg := &TargetsConfig{Targets: []Target{validTarget()}, People: []Person{p}}
+	if err := validateTargetsConfig(cfg); err != nil {
+		t.Fatalf("valid person rejected: %v", err)
+	}
+}
+
+func TestValidatePeopleErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*TargetsConfig)
+	}{
+		{"empty name", func(c *TargetsConfig) { c.People[0].Name = "" }},
+		{"duplicate name", func(c *TargetsConfig) { c.People = append(c.People, validPerson()) }},
+		{"bad presence", func(c *TargetsConfig) { c.People[0].Presence = "most" }},
+		{"no devices", func(c *TargetsConfig) { c.People[0].Devices = nil }},
+		{"unknown device", func(c *TargetsConfig) { c.People[0].Devices = []string{"11:22:33:44:55:66"} }},
+		{"shared device", func(c *TargetsConfig) {
+			other := validPerson()
+			other.Name = "Mom"
+			c.People = append(c.People, other)
+		}},
+		{"empty receiver id", func(c *TargetsConfig) { c.People[0].Receivers = []Receiver{{ID: ""}} }},
+		{"bad quiet hours", func(c *TargetsConfig) {
+			c.People[0].QuietHours = Schedule{Windows: []TimeWindow{{Start: "25:00", End: "07:00"}}}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &TargetsConfig{Targets: []Target{validTarget()}, People: []Person{validPerson()}}
+			tt.mutate(cfg)
+			if err := validateTargetsConfig(cfg); err == nil {
+				t.Errorf("expected error for %s, got nil", tt.name)
+			}
+		})
+	}
+}
+
+func TestPersonMessageFor(t *testing.T) {
+	p := Person{Message: "person-msg"}
+	if got := p.MessageFor(Receiver{Message: "recv-msg"}, "default"); got != "recv-msg" {
+		t.Errorf("receiver message should win: got %q", got)
+	}
+	if got := p.MessageFor(Receiver{}, "default"); got != "person-msg" {
+		t.Errorf("person message should win over default: got %q", got)
+	}
+	if got := (Person{}).MessageFor(Receiver{}, "default"); got != "default" {
+		t.Errorf("default should be used: got %q", got)
+	}
+}
+
+func TestPersonQuietHoursFor(t *testing.T) {
+	night := Schedule{Windows: []TimeWindow{{Start: "22:00", End: "07:00"}}}
+	target := validTarget()
+	target.QuietHours = night
+
+	p := validPerson()
+	if got := p.QuietHoursFor([]Target{target}); len(got.Windows) != 1 {
+		t.Errorf("person without quiet hours should use the device's, got %+v", got)
+	}
+	p.QuietHours = Schedule{Action: QuietDefer, Windows: []TimeWindow{{Start: "23:00", End: "06:00"}}}
+	if got := p.QuietHoursFor([]Target{target}); got.Action != QuietDefer {
+		t.Errorf("person's own quiet hours should win, got %+v", got)
+	}
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Rule triggers.
+const (
+	TriggerArrival           = "arrival"            // a target or person arrived
+	TriggerDeparture         = "departure"          // a target or person left
+	TriggerHouseholdEmpty    = "household_empty"    // the last household member left
+	TriggerHouseholdOccupied = "household_occupied" // the first household member arrived
+	TriggerUnknownDevice     = "unknown_device"     // a never-seen device joined the LAN
+)
+
+// Rule action types.
+const (
+	ActionNotify  = "notify"  // send a message to receivers
+	ActionWebhook = "webhook" // POST the event as JSON to a URL
+	ActionCommand = "command" // run a program (no shell)
+)
+
+// RulesConfig holds the rule settings of config.yaml.
+type RulesConfig struct {
+	// AllowCommands lets command actions run programs on the host. It is off
+	// by default and cannot be changed through the web API, since anyone who
+	// can reach the admin UI could otherwise run code on the host.
+	AllowCommands bool `yaml:"allow_commands" json:"allow_commands"`
+}
+
+// Rule runs its actions when its trigger fires and every condition holds.
+type Rule struct {
+	Name     string         `yaml:"name" json:"name"`
+	Enabled  bool           `yaml:"enabled" json:"enabled"`
+	Trigger  string         `yaml:"trigger" json:"trigger"`
+	Subjects []string       `yaml:"subjects,omitempty" json:"subjects"` // target names/MACs or person names; empty = any
+	When     RuleConditions `yaml:"when,omitempty" json:"when"`
+	Actions  []RuleAction   `yaml:"actions" json:"actions"`
+}
+
+// RuleConditions must all hold for a rule to run. Empty fields always hold.
+type RuleConditions struct {
+	Timezone string       `yaml:"timezone,omitempty" json:"timezone"`
+	Windows  []TimeWindow `yaml:"windows,omitempty" json:"windows"` // the event falls in one of these
+	Days     []string     `yaml:"days,omitempty" json:"days"`       // mon..sun
+	Present  []string     `yaml:"present,omitempty" json:"present"` // these targets/people are home
+	Absent   []string     `yaml:"absent,omitempty" json:"absent"`   // these targets/people are away
+}
+
+// Schedule returns the conditions' time windows as a schedule.
+func (c RuleConditions) Schedule() Schedule {
+	return Schedule{Timezone: c.Timezone, Windows: c.Windows}
+}
+
+// RuleAction is one thing a rule does. Message and command arguments are
+// text/template strings rendered with the triggering event.
+type RuleAction struct {
+	Type      string     `yaml:"type" json:"type"`
+	Receivers []Receiver `yaml:"receivers,omitempty" json:"receivers"` // notify
+	Message   string     `yaml:"message,omitempty" json:"message"`     // notify
+	URL       string     `yaml:"url,omitempty" json:"url"`             // webhook
+	Command   []string   `yaml:"command,omitempty" json:"command"`     // command: program and arguments
+}
+
+func validateRules(cfg *TargetsConfig) error {
+	names := make(map[string]bool)
+	for _, t := range cfg.Targets {
+		names[strings.ToLower(t.Name)] = true
+		names[strings.ToLower(t.Mac)] = true
+	}
+	for _, p := range cfg.People {
+		names[strings.ToLower(p.Name)] = true
+	}
+	checkNames := func(rule, field string, list []string) error {
+		for _, n := range list {
+			if !names[strings.ToLower(n)] {
+				return fmt.Errorf("rule %s: %s: %q is not a target or person", rule, field, n)
+			}
+		}
+		return nil
+	}
+
+	for i, r := range cfg.Rules {
+		label := r.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+1)
+		}
+		switch r.Trigger {
+		case TriggerArrival, TriggerDeparture, TriggerHouseholdEmpty, TriggerHouseholdOccupied, TriggerUnknownDevice:
+		default:
+			return fmt.Errorf("rule %s: invalid trigger %q (expected arrival|departure|household_empty|household_occupied|unknown_device)", label, r.Trigger)
+		}
+		if err := checkNames(label, "subjects", r.Subjects); err != nil {
+			return err
+		}
+		if err := checkNames(label, "when.present", r.When.Present); err != nil {
+			return err
+		}
+		if err := checkNames(label, "when.absent", r.When.Absent); err != nil {
+			return err
+		}
+		if err := validateSchedule(r.When.Schedule()); err != nil {
+			return fmt.Errorf("rule %s: when: %w", label, err)
+		}
+		for _, d := range r.When.Days {
+			if _, ok := weekdays[strings.ToLower(d)]; !ok {
+				return fmt.Errorf("rule %s: when.days: invalid day %q (expected mon..sun)", label, d)
+			}
+		}
+
+		if len(r.Actions) == 0 {
+			return fmt.Errorf("rule %s: at least one action is required", label)
+		}
+		for j, a := range r.Actions {
+			if err := validateAction(a); err != nil {
+				return fmt.Errorf("rule %s: action #%d: %w", label, j+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateAction(a RuleAction) error {
+	var templates []string
+	switch a.Type {
+	case ActionNotify:
+		if len(a.Receivers) == 0 {
+			return fmt.Errorf("notify needs receivers")
+		}
+		for j, r := range a.Receivers {
+			if err := r.validate(); err != nil {
+				return fmt.Errorf("receiver #%d: %w", j+1, err)
+			}
+		}
+		templates = append(templates, a.Message)
+	case ActionWebhook:
+		u, err := url.Parse(a.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook url %q", a.URL)
+		}
+	case ActionCommand:
+		if len(a.Command) == 0 || a.Command[0] == "" {
+			return fmt.Errorf("command is empty")
+		}
+		templates = append(templates, a.Command...)
+	default:
+		return fmt.Errorf("invalid type %q (expected notify|webhook|command)", a.Type)
+	}
+	for _, t := range templates {
+		if err := validateMessage(t); err != nil {
+			return fmt.Errorf("%q: %w", t, err)
+		}
+	}
+	return nil
+}
+
+// checkRuleCommands rejects command actions unless rules.allow_commands is set
+// in config.yaml.
+func checkRuleCommands(rules []Rule, allow bool) error {
+	if allow {
+		return nil
+	}
+	for i, r := range rules {
+		for _, a := range r.Actions {
+			if a.Type != ActionCommand {
+				continue
+			}
+			label := r.Name
+			if label == "" {
+				label = fmt.Sprintf("#%d", i+1)
+			}
+			return fmt.Errorf("rule %s: command actions are disabled (set rules.allow_commands in config.yaml)", label)
+		}
+	}
+	return nil
+}
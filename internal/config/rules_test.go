@@ -0,0 +1,69 @@
+package config
+
+import "testing"
+
+func validRule() Rule {
+	return Rule{
+		Name:     "porch light",
+		Enabled:  true,
+		Trigger:  TriggerArrival,
+		Subjects: []string{"Phone"},
+		When: RuleConditions{
+			Windows: []TimeWindow{{Start: "18:00", End: "06:00"}},
+			Days:    []string{"mon", "Fri"},
+			Absent:  []string{"AA:BB:CC:DD:EE:FF"},
+		},
+		Actions: []RuleAction{
+			{Type: ActionNotify, Receivers: []Receiver{{ID: "U1"}}, Message: "{{.Name}} is home"},
+			{Type: ActionWebhook, URL: "http://homeassistant.local/api/webhook/x"},
+			{Type: ActionCommand, Command: []string{"/usr/bin/logger", "{{.Mac}}"}},
+		},
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	cfg := &TargetsConfig{Targets: []Target{validTarget()}, Rules: []Rule{validRule()}}
+	if err := validateTargetsConfig(cfg); err != nil {
+		t.Errorf("valid rule rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Rule)
+	}{
+		{"bad trigger", func(r *Rule) { r.Trigger = "arrive" }},
+		{"unknown subject", func(r *Rule) { r.Subjects = []string{"Nobody"} }},
+		{"unknown present", func(r *Rule) { r.When.Present = []string{"Nobody"} }},
+		{"bad window", func(r *Rule) { r.When.Windows[0].Start = "25:00" }},
+		{"bad day", func(r *Rule) { r.When.Days = []string{"someday"} }},
+		{"no actions", func(r *Rule) { r.Actions = nil }},
+		{"bad action type", func(r *Rule) { r.Actions[0].Type = "email" }},
+		{"notify without receivers", func(r *Rule) { r.Actions[0].Receivers = nil }},
+		{"bad template", func(r *Rule) { r.Actions[0].Message = "{{.Name" }},
+		{"unknown template field", func(r *Rule) { r.Actions[0].Message = "{{.Subject}} is home" }},
+		{"bad webhook url", func(r *Rule) { r.Actions[1].URL = "ftp://example.com" }},
+		{"empty command", func(r *Rule) { r.Actions[2].Command = nil }},
+	}
+	for _, tt := range tests {
+		r := validRule()
+		tt.mutate(&r)
+		cfg := &TargetsConfig{Targets: []Target{validTarget()}, Rules: []Rule{r}}
+		if err := validateTargetsConfig(cfg); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestCheckRuleCommands(t *testing.T) {
+	rules := []Rule{validRule()}
+	if err := checkRuleCommands(rules, false); err == nil {
+		t.Error("command action accepted without rules.allow_commands")
+	}
+	if err := checkRuleCommands(rules, true); err != nil {
+		t.Errorf("command action rejected with rules.allow_commands: %v", err)
+	}
+	rules[0].Actions = rules[0].Actions[:2]
+	if err := checkRuleCommands(rules, false); err != nil {
+		t.Errorf("rule without commands rejected: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+	_ "time/tzdata" // timezone names must resolve even on hosts without zoneinfo
+)
+
+// Quiet-hours actions.
+const (
+	QuietSuppress = "suppress" // drop notifications that fall inside a window
+	QuietDefer    = "defer"    // hold notifications until the window ends
+)
+
+// Schedule is a set of weekly time windows evaluated in one timezone.
+type Schedule struct {
+	Timezone string       `yaml:"timezone,omitempty" json:"timezone"`
+	Action   string       `yaml:"action,omitempty" json:"action"`
+	Windows  []TimeWindow `yaml:"windows,omitempty" json:"windows"`
+}
+
+// TimeWindow is a daily time range on the given days. An end before the start
+// wraps past midnight, with Days naming the day the window starts on.
+type TimeWindow struct {
+	Days  []string `yaml:"days,omitempty" json:"days"` // mon..sun; empty = every day
+	Start string   `yaml:"start" json:"start"`         // HH:MM
+	End   string   `yaml:"end" json:"end"`             // HH:MM
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Location returns the schedule's timezone, defaulting to the local zone.
+func (s Schedule) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Local // rejected by validation; only reachable for unsaved configs
+	}
+	return loc
+}
+
+// ActionOrDefault returns the configured action, defaulting to suppress.
+func (s Schedule) ActionOrDefault() string {
+	if s.Action == "" {
+		return QuietSuppress
+	}
+	return s.Action
+}
+
+// ActiveAt reports whether t falls inside any window and, if so, when the
+// latest-ending window containing t closes.
+func (s Schedule) ActiveAt(t time.Time) (bool, time.Time) {
+	t = t.In(s.Location())
+	var until time.Time
+	for _, w := range s.Windows {
+		if end, ok := w.endAfter(t); ok && end.After(until) {
+			until = end
+		}
+	}
+	return !until.IsZero(), until
+}
+
+// endAfter reports whether t falls inside an occurrence of the window and when
+// that occurrence ends.
+func (w TimeWindow) endAfter(t time.Time) (time.Time, bool) {
+	for start, end := range w.occurrences(t) {
+		if !t.Before(start) && t.Before(end) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// occurrences yields the window's occurrences that started on t's day or on
+// the two days before, in t's location. That covers every occurrence that can
+// contain t or have ended shortly before it, overnight windows included.
+func (w TimeWindow) occurrences(t time.Time) iter.Seq2[time.Time, time.Time] {
+	startMin, _ := parseClock(w.Start)
+	endMin, _ := parseClock(w.End)
+	length := endMin - startMin
+	if length <= 0 {
+		length += 24 * 60 // overnight, or a full day when start == end
+	}
+	return func(yield func(start, end time.Time) bool) {
+		for offset := -2; offset <= 0; offset++ {
+			day := t.AddDate(0, 0, offset)
+			if !w.onDay(day.Weekday()) {
+				continue
+			}
+			start := time.Date(day.Year(), day.Month(), day.Day(), startMin/60, startMin%60, 0, 0, t.Location())
+			if !yield(start, start.Add(time.Duration(length)*time.Minute)) {
+				return
+			}
+		}
+	}
+}
+
+func (w TimeWindow) onDay(d time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, name := range w.Days {
+		if wd, ok := weekdays[strings.ToLower(name)]; ok && wd == d {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses "HH:MM" into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func validateSchedule(s Schedule) error {
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", s.Timezone)
+		}
+	}
+	switch s.Action {
+	case "", QuietSuppress, QuietDefer:
+	default:
+		return fmt.Errorf("invalid action %q (expected suppress|defer)", s.Action)
+	}
+	for i, w := range s.Windows {
+		if err := validateWindow(w); err != nil {
+			return fmt.Errorf("window #%d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func validateWindow(w TimeWindow) error {
+	if _, err := parseClock(w.Start); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	if _, err := parseClock(w.End); err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	for _, d := range w.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("invalid day %q (expected mon..sun)", d)
+		}
+	}
+	return nil
+}
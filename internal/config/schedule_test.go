@@ -0,0 +1,111 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleActiveAtOvernightWindow(t *testing.T) {
+	s := Schedule{
+		Timezone: "Asia/Taipei",
+		Windows:  []TimeWindow{{Days: []string{"fri"}, Start: "22:00", End: "07:00"}},
+	}
+	loc, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+
+	// 2026-10-16 is a Friday.
+	tests := []struct {
+		name   string
+		at     time.Time
+		active bool
+	}{
+		{"friday before start", time.Date(2026, 10, 16, 21, 59, 0, 0, loc), false},
+		{"friday at start", time.Date(2026, 10, 16, 22, 0, 0, 0, loc), true},
+		{"saturday early morning", time.Date(2026, 10, 17, 3, 0, 0, 0, loc), true},
+		{"saturday at end", time.Date(2026, 10, 17, 7, 0, 0, 0, loc), false},
+		{"saturday night", time.Date(2026, 10, 17, 23, 0, 0, 0, loc), false},
+		{"thursday night", time.Date(2026, 10, 15, 23, 0, 0, 0, loc), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, _ := s.ActiveAt(tt.at)
+			if active != tt.active {
+				t.Errorf("ActiveAt(%s) = %v, want %v", tt.at, active, tt.active)
+			}
+		})
+	}
+
+	_, until := s.ActiveAt(time.Date(2026, 10, 17, 3, 0, 0, 0, loc))
+	if want := time.Date(2026, 10, 17, 7, 0, 0, 0, loc); !until.Equal(want) {
+		t.Errorf("until = %s, want %s", until, want)
+	}
+}
+
+func TestScheduleActiveAtUsesTimezone(t *testing.T) {
+	s := Schedule{Timezone: "Asia/Taipei", Windows: []TimeWindow{{Start: "09:00", End: "10:00"}}}
+
+	// 01:30 UTC is 09:30 in Taipei (UTC+8).
+	if active, _ := s.ActiveAt(time.Date(2026, 10, 16, 1, 30, 0, 0, time.UTC)); !active {
+		t.Error("window should be evaluated in the schedule's timezone")
+	}
+}
+
+func TestScheduleEmptyNeverActive(t *testing.T) {
+	if active, _ := (Schedule{}).ActiveAt(time.Now()); active {
+		t.Error("a schedule without windows should never be active")
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	valid := Schedule{
+		Timezone: "Europe/Berlin",
+		Action:   QuietDefer,
+		Windows:  []TimeWindow{{Days: []string{"Mon", "sun"}, Start: "22:00", End: "06:30"}},
+	}
+	if err := validateSchedule(valid); err != nil {
+		t.Fatalf("valid schedule rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Schedule)
+	}{
+		{"bad timezone", func(s *Schedule) { s.Timezone = "Mars/Olympus" }},
+		{"bad action", func(s *Schedule) { s.Action = "ignore" }},
+		{"bad start", func(s *Schedule) { s.Windows[0].Start = "25:00" }},
+		{"bad end", func(s *Schedule) { s.Windows[0].End = "7am" }},
+		{"bad day", func(s *Schedule) { s.Windows[0].Days = []string{"someday"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid
+			s.Windows = []TimeWindow{valid.Windows[0]}
+			tt.mutate(&s)
+			if err := validateSchedule(s); err == nil {
+				t.Errorf("expected error for %s, got nil", tt.name)
+			}
+		})
+	}
+}
+
+func TestExpectationClosedBetween(t *testing.T) {
+	e := Expectation{
+		Timezone: "UTC",
+		Windows:  []TimeWindow{{Days: []string{"mon"}, Start: "15:00", End: "18:00"}},
+	}
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC) // a Monday
+
+	got := e.ClosedBetween(monday.Add(17*time.Hour), monday.Add(19*time.Hour))
+	if len(got) != 1 || !got[0].Start.Equal(monday.Add(15*time.Hour)) || !got[0].End.Equal(monday.Add(18*time.Hour)) {
+		t.Fatalf("ClosedBetween = %+v, want Monday 15:00-18:00", got)
+	}
+	if got := e.ClosedBetween(monday.Add(18*time.Hour), monday.Add(19*time.Hour)); len(got) != 0 {
+		t.Errorf("a window that ended at the lower bound was already reported, got %+v", got)
+	}
+	tuesday := monday.AddDate(0, 0, 1)
+	if got := e.ClosedBetween(tuesday.Add(17*time.Hour), tuesday.Add(19*time.Hour)); len(got) != 0 {
+		t.Errorf("the window only applies on Mondays, got %+v", got)
+	}
+}
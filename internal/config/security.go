@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"net"
+)
+
+// Security configures the ARP spoofing / IP conflict monitor, which inspects
+// the IP-to-MAC bindings of every broadcast scan.
+type Security struct {
+	Enabled      bool       `yaml:"enabled" json:"enabled"`
+	GatewayIP    string     `yaml:"gateway_ip,omitempty" json:"gateway_ip"`
+	MaxIPsPerMac int        `yaml:"max_ips_per_mac,omitempty" json:"max_ips_per_mac"`
+	Receivers    []Receiver `yaml:"receivers" json:"receivers"`
+}
+
+// DefaultMaxIPsPerMac is used when max_ips_per_mac is unset.
+const DefaultMaxIPsPerMac = 4
+
+// IPLimit returns the number of IPs one MAC may answer for in a single scan
+// before it is reported.
+func (s Security) IPLimit() int {
+	if s.MaxIPsPerMac > 0 {
+		return s.MaxIPsPerMac
+	}
+	return DefaultMaxIPsPerMac
+}
+
+func validateSecurity(s Security) error {
+	if s.GatewayIP != "" && net.ParseIP(s.GatewayIP) == nil {
+		return fmt.Errorf("security: invalid gateway_ip %q", s.GatewayIP)
+	}
+	if s.MaxIPsPerMac < 0 {
+		return fmt.Errorf("security: max_ips_per_mac must be >= 0 (0 = %d)", DefaultMaxIPsPerMac)
+	}
+	for j, r := range s.Receivers {
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("security: receiver #%d: %w", j+1, err)
+		}
+		if err := validateSchedule(r.QuietHours); err != nil {
+			return fmt.Errorf("security: receiver #%d quiet_hours: %w", j+1, err)
+		}
+	}
+	return nil
+}
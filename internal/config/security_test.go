@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestValidateSecurity(t *testing.T) {
+	valid := Security{Enabled: true, GatewayIP: "192.168.0.1", Receivers: []Receiver{{ID: "U1"}}}
+	if err := validateSecurity(valid); err != nil {
+		t.Errorf("valid security config rejected: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  Security
+	}{
+		{"bad gateway ip", Security{GatewayIP: "router"}},
+		{"negative max ips", Security{MaxIPsPerMac: -1}},
+		{"empty receiver id", Security{Receivers: []Receiver{{ID: ""}}}},
+	}
+	for _, tt := range tests {
+		if err := validateSecurity(tt.cfg); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+
+	if got := (Security{}).IPLimit(); got != DefaultMaxIPsPerMac {
+		t.Errorf("IPLimit() = %d, want default %d", got, DefaultMaxIPsPerMac)
+	}
+}
@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func validTarget() Target {
 	return Target{
@@ -44,6 +47,17 @@ func TestValidateTargetsConfigErrors(t *testing.T) {
 		{"empty mode", func(t *Target) { t.Detection = Detection{Mode: "", IP: "192.168.0.2"} }},
 		{"unknown mode", func(t *Target) { t.Detection = Detection{Mode: "magic", IP: "192.168.0.2"} }},
 		{"empty receiver id", func(t *Target) { t.Receivers = []Receiver{{ID: ""}} }},
+		{"negative presence policy", func(t *Target) { t.Policy.MissThreshold = -1 }},
+		{"bad quiet hours", func(t *Target) {
+			t.QuietHours = Schedule{Windows: []TimeWindow{{Start: "22:00", End: "late"}}}
+		}},
+		{"negative away alert", func(t *Target) { t.Expect.AwayAlertHours = -1 }},
+		{"bad expect window", func(t *Target) {
+			t.Expect = Expectation{Windows: []TimeWindow{{Days: []string{"someday"}, Start: "15:00", End: "18:00"}}}
+		}},
+		{"bad receiver quiet hours", func(t *Target) {
+			t.Receivers = []Receiver{{ID: "U1", QuietHours: Schedule{Timezone: "Nowhere/Land"}}}
+		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -57,6 +71,17 @@ func TestValidateTargetsConfigErrors(t *testing.T) {
 	}
 }
 
+func TestValidateTargetsConfigAdmins(t *testing.T) {
+	cfg := &TargetsConfig{Admins: []Receiver{{ID: "U1"}}}
+	if err := validateTargetsConfig(cfg); err != nil {
+		t.Errorf("valid admins rejected: %v", err)
+	}
+	cfg.Admins = append(cfg.Admins, Receiver{})
+	if err := validateTargetsConfig(cfg); err == nil {
+		t.Error("expected error for an admin with an empty id")
+	}
+}
+
 func TestValidateTargetsConfigEmptyContactID(t *testing.T) {
 	cfg := &TargetsConfig{
 		Contacts: []Contact{{ID: "", Name: "Nobody"}},
@@ -82,3 +107,21 @@ func TestMessageFor(t *testing.T) {
 		t.Errorf("default should be used: got %q", got)
 	}
 }
+
+func TestValidateLineFlex(t *testing.T) {
+	tgt := validTarget()
+	tgt.LineFlex = LineFlex{Enabled: true, Color: "#3fb950", Fields: []string{FlexFieldTime, FlexFieldIP}, ButtonLabel: strings.Repeat("狀", 40)}
+	if err := validateTargetsConfig(&TargetsConfig{Targets: []Target{tgt}}); err != nil {
+		t.Fatalf("valid line_flex rejected: %v", err)
+	}
+	for _, bad := range []LineFlex{
+		{Enabled: true, Color: "green"},
+		{Enabled: true, Fields: []string{"battery"}},
+		{Enabled: true, ButtonLabel: strings.Repeat("x", 41)},
+	} {
+		tgt.LineFlex = bad
+		if err := validateTargetsConfig(&TargetsConfig{Targets: []Target{tgt}}); err == nil {
+			t.Errorf("%+v should be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MessageData is what arrival and departure messages (default_message and
+// the target, person and receiver messages) and rule actions are rendered with.
+type MessageData struct {
+	Name     string        // target or person
+	Event    string        // arrival or departure, or a rule's trigger
+	Time     time.Time     // when it happened
+	Mac      string        // the device's MAC, when there is one
+	IP       string        // the device's IP, when known
+	AwayFor  time.Duration // how long the subject was away before arriving; 0 if unknown
+	Receiver string        // the receiver's contact name, or its ID
+}
+
+// sampleMessageData is what templates are test-rendered with when they are
+// validated and what the preview fills in for fields it is not given.
+var sampleMessageData = MessageData{
+	Name:     "Amy",
+	Event:    TriggerArrival,
+	Time:     time.Date(2026, 1, 2, 18, 30, 0, 0, time.Local),
+	Mac:      "aa:bb:cc:dd:ee:ff",
+	IP:       "192.168.0.100",
+	AwayFor:  3*time.Hour + 20*time.Minute,
+	Receiver: "Mom",
+}
+
+// SampleMessageData returns the example event templates are previewed with.
+func SampleMessageData() MessageData {
+	return sampleMessageData
+}
+
+// messageTime is MessageData.Time as templates see it: in local time, printed
+// as "2006-01-02 15:04" by {{.Time}}. Its time.Time methods stay available.
+type messageTime struct{ time.Time }
+
+func (t messageTime) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// messageDuration is MessageData.AwayFor as templates see it, printed as
+// "3h 20m" by {{.AwayFor}}.
+type messageDuration time.Duration
+
+func (d messageDuration) String() string   { return HumanDuration(time.Duration(d)) }
+func (d messageDuration) Hours() float64   { return time.Duration(d).Hours() }
+func (d messageDuration) Minutes() float64 { return time.Duration(d).Minutes() }
+
+// messageView is the data a message template is executed with.
+type messageView struct {
+	Name     string
+	Event    string
+	Time     messageTime
+	Mac      string
+	IP       string
+	AwayFor  messageDuration
+	Receiver string
+}
+
+func (data MessageData) view() messageView {
+	t := data.Time
+	if !t.IsZero() {
+		// Drop the monotonic clock reading that time.Now() carries.
+		t = t.Round(0).Local()
+	}
+	return messageView{
+		Name:     data.Name,
+		Event:    data.Event,
+		Time:     messageTime{t},
+		Mac:      data.Mac,
+		IP:       data.IP,
+		AwayFor:  messageDuration(data.AwayFor),
+		Receiver: data.Receiver,
+	}
+}
+
+var messageFuncs = template.FuncMap{
+	"format":   func(layout string, t messageTime) string { return t.Format(layout) },
+	"clock":    func(t messageTime) string { return t.Format("15:04") },
+	"date":     func(t messageTime) string { return t.Format("2006-01-02") },
+	"duration": func(d messageDuration) string { return d.String() },
+	"greeting": func(t messageTime) string { return greeting(t.Time) },
+}
+
+// ParseMessage parses a message template. {{.Time}} prints as
+// "2006-01-02 15:04" and {{.AwayFor}} as "3h 20m". Besides the standard
+// functions it offers format (format "Mon 15:04" .Time), clock and date (.Time
+// as 15:04 and 2006-01-02), duration (.AwayFor as "3h 20m") and greeting
+// ("Good evening", from the hour of a time).
+func ParseMessage(text string) (*template.Template, error) {
+	return template.New("message").Funcs(messageFuncs).Parse(text)
+}
+
+// RenderMessage fills a message template. Templates were checked by config
+// validation, so a failure only falls back to the raw text.
+func RenderMessage(text string, data MessageData) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	out, err := ExecuteMessage(text, data)
+	if err != nil {
+		return text
+	}
+	return out
+}
+
+// ExecuteMessage renders a message template, reporting parse and execution
+// errors.
+func ExecuteMessage(text string, data MessageData) (string, error) {
+	t, err := ParseMessage(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data.view()); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// validateMessage checks that a message template parses and renders, so that
+// typos such as {{.Nmae}} are caught at save time rather than at send time.
+func validateMessage(text string) error {
+	if _, err := ExecuteMessage(text, sampleMessageData); err != nil {
+		return fmt.Errorf("invalid message template: %w", err)
+	}
+	return nil
+}
+
+// HumanDuration formats a duration coarsely: "45m", "3h 20m", "2d 4h". Message
+// templates and LINE Flex cards print durations with it.
+func HumanDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days, hours, mins := int(d/(24*time.Hour)), int(d/time.Hour)%24, int(d/time.Minute)%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, mins)
+	}
+	return fmt.Sprintf("%dm", mins)
+}
+
+// greeting returns a greeting for the time of day.
+func greeting(t time.Time) string {
+	switch h := t.Hour(); {
+	case h >= 5 && h < 12:
+		return "Good morning"
+	case h >= 12 && h < 18:
+		return "Good afternoon"
+	case h >= 18 && h < 22:
+		return "Good evening"
+	}
+	return "Good night"
+}
+
+// ContactName returns the contacts registry's name for a receiver ID, or the
+// ID itself.
+func (cfg TargetsConfig) ContactName(id string) string {
+	for _, c := range cfg.Contacts {
+		if SameReceiver(c.ID, id) && c.Name != "" {
+			return c.Name
+		}
+	}
+	return id
+}
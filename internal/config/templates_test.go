@@ -0,0 +1,78 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMessage(t *testing.T) {
+	data := MessageData{
+		Name:     "Amy",
+		Event:    TriggerArrival,
+		Time:     time.Date(2026, 3, 6, 7, 5, 0, 0, time.Local),
+		IP:       "192.168.0.7",
+		AwayFor:  26*time.Hour + 10*time.Minute,
+		Receiver: "Mom",
+	}
+	tests := []struct{ text, want string }{
+		{"Welcome home!", "Welcome home!"},
+		{"{{greeting .Time}}, {{.Receiver}}! {{.Name}} is home.", "Good morning, Mom! Amy is home."},
+		{"{{.Name}} ({{.IP}}) {{.Event}} at {{clock .Time}} on {{date .Time}}", "Amy (192.168.0.7) arrival at 07:05 on 2026-03-06"},
+		{`{{format "Mon 3:04PM" .Time}}, away {{duration .AwayFor}}`, "Fri 7:05AM, away 1d 2h"},
+		{"{{if gt .AwayFor.Hours 24.0}}Long trip!{{end}}", "Long trip!"},
+		{"{{.Time}}, away {{.AwayFor}}", "2026-03-06 07:05, away 1d 2h"},
+	}
+	for _, tt := range tests {
+		if got := RenderMessage(tt.text, data); got != tt.want {
+			t.Errorf("RenderMessage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRenderMessageTimeFromNow(t *testing.T) {
+	now := time.Now()
+	got := RenderMessage("{{.Time}} ({{.AwayFor}})", MessageData{Time: now, AwayFor: 3*time.Hour + 20*time.Minute + 5*time.Second})
+	want := now.Local().Format("2006-01-02 15:04") + " (3h 20m)"
+	if got != want {
+		t.Errorf("rendered %q, want %q", got, want)
+	}
+}
+
+func TestGreetingAndDuration(t *testing.T) {
+	for hour, want := range map[int]string{4: "Good night", 5: "Good morning", 12: "Good afternoon", 18: "Good evening", 22: "Good night"} {
+		if got := greeting(time.Date(2026, 1, 1, hour, 0, 0, 0, time.UTC)); got != want {
+			t.Errorf("greeting(%d:00) = %q, want %q", hour, got, want)
+		}
+	}
+	for d, want := range map[time.Duration]string{0: "0m", 45 * time.Minute: "45m", 3*time.Hour + 20*time.Minute: "3h 20m", 50 * time.Hour: "2d 2h"} {
+		if got := HumanDuration(d); got != want {
+			t.Errorf("HumanDuration(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestValidateMessageTemplates(t *testing.T) {
+	cfg := &TargetsConfig{DefaultMessage: "{{greeting .Time}}, {{.Name}} is home", Targets: []Target{validTarget()}}
+	if err := validateTargetsConfig(cfg); err != nil {
+		t.Fatalf("valid template rejected: %v", err)
+	}
+
+	for _, bad := range []string{"{{.Name", "{{.Nmae}}", "{{shout .Name}}"} {
+		cfg.Targets[0].Receivers[0].Message = bad
+		err := validateTargetsConfig(cfg)
+		if err == nil || !strings.Contains(err.Error(), "receiver #1 message") {
+			t.Errorf("%q: want a receiver message error, got %v", bad, err)
+		}
+	}
+}
+
+func TestContactName(t *testing.T) {
+	cfg := TargetsConfig{Contacts: []Contact{{ID: "U123", Name: "Mom"}}}
+	if got := cfg.ContactName("line:U123"); got != "Mom" {
+		t.Errorf("ContactName = %q, want Mom", got)
+	}
+	if got := cfg.ContactName("telegram:42"); got != "telegram:42" {
+		t.Errorf("unknown contact = %q, want its ID", got)
+	}
+}
@@ -0,0 +1,219 @@
+// Package discord delivers notifications to Discord channels through
+// webhooks, as embeds showing who, when and from which IP.
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+// maxWait is the longest a post waits for a rate limit to reset; longer limits
+// fail the attempt and leave the retry to the outbox.
+const maxWait = 10 * time.Second
+
+// Embed colors, matching the admin UI palette.
+var colors = map[string]int{
+	config.TriggerArrival:   0x3fb950,
+	config.TriggerDeparture: 0xf0506e,
+	"alert":                 0xf0a030,
+}
+
+var titles = map[string]string{
+	config.TriggerArrival:           "Arrival",
+	config.TriggerDeparture:         "Departure",
+	config.TriggerHouseholdEmpty:    "House empty",
+	config.TriggerHouseholdOccupied: "House occupied",
+	config.TriggerUnknownDevice:     "New device",
+	"alert":                         "Alert",
+	"test":                          "Test notification",
+}
+
+// Notifier posts to the webhooks named in the targets config. It reads the
+// config on every send, so webhook changes apply without a restart.
+type Notifier struct {
+	config func() config.DiscordConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	blocked map[string]time.Time // webhook URL -> when its rate limit resets
+}
+
+// New returns a notifier reading its webhooks from cfg.
+func New(cfg func() config.DiscordConfig) *Notifier {
+	return &Notifier{
+		config:  cfg,
+		client:  &http.Client{Timeout: 15 * time.Second},
+		blocked: make(map[string]time.Time),
+	}
+}
+
+type embedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type embed struct {
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Color       int          `json:"color,omitempty"`
+	Timestamp   string       `json:"timestamp,omitempty"`
+	Fields      []embedField `json:"fields,omitempty"`
+}
+
+type payload struct {
+	Username string  `json:"username,omitempty"`
+	Embeds   []embed `json:"embeds"`
+}
+
+func buildPayload(username string, msg notifier.Message) payload {
+	e := embed{Title: titles[msg.Kind], Description: msg.Text, Color: colors[msg.Kind]}
+	if e.Title == "" {
+		e.Title = "arp-notify"
+	}
+	if e.Color == 0 {
+		e.Color = 0x4f8cff
+	}
+	if msg.Subject != "" {
+		e.Fields = append(e.Fields, embedField{Name: "Who", Value: msg.Subject, Inline: true})
+	}
+	if !msg.Time.IsZero() {
+		e.Timestamp = msg.Time.UTC().Format(time.RFC3339)
+		// Discord renders <t:unix:f> in each reader's own timezone.
+		e.Fields = append(e.Fields, embedField{Name: "Time", Value: fmt.Sprintf("<t:%d:f>", msg.Time.Unix()), Inline: true})
+	}
+	if msg.IP != "" {
+		e.Fields = append(e.Fields, embedField{Name: "IP", Value: msg.IP, Inline: true})
+	}
+	if msg.Mac != "" {
+		e.Fields = append(e.Fields, embedField{Name: "MAC", Value: msg.Mac, Inline: true})
+	}
+	return payload{Username: username, Embeds: []embed{e}}
+}
+
+// Configured reports whether any Discord webhook is set up.
+func (n *Notifier) Configured() bool {
+	return len(n.config().Webhooks) > 0
+}
+
+// Send makes one post of the message to the named webhook. It waits out a
+// short rate limit reported by an earlier response (an exhausted bucket or a
+// 429's retry_after) first; the outbox retries failures. Responses other than
+// 429 and 5xx fail with notifier.ErrPermanent.
+func (n *Notifier) Send(ctx context.Context, name string, msg notifier.Message) error {
+	cfg := n.config()
+	url, ok := cfg.Webhooks[name]
+	if !ok {
+		return fmt.Errorf("no discord webhook named %q (%w)", name, notifier.ErrPermanent)
+	}
+	body, err := json.Marshal(buildPayload(cfg.Username, msg))
+	if err != nil {
+		return err
+	}
+
+	wait := n.waitFor(url)
+	if wait > maxWait {
+		return fmt.Errorf("discord webhook %q rate limited (retry after %s)", name, wait.Round(time.Second))
+	}
+	if err := sleep(ctx, wait); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	n.noteLimits(url, resp.Header)
+	status := resp.StatusCode
+	retryAfter := retryAfter(resp)
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	resp.Body.Close()
+
+	switch {
+	case status < 300:
+		return nil
+	case status == http.StatusTooManyRequests:
+		n.block(url, retryAfter)
+		log.Printf("Discord webhook %q rate limited for %s.", name, retryAfter)
+		return fmt.Errorf("discord webhook %q rate limited (retry after %s)", name, retryAfter)
+	case status >= 500:
+		return fmt.Errorf("discord webhook %q returned %d: %s", name, status, bytes.TrimSpace(snippet))
+	}
+	return fmt.Errorf("discord webhook %q returned %d: %s (%w)", name, status, bytes.TrimSpace(snippet), notifier.ErrPermanent)
+}
+
+// waitFor returns how long to wait before posting to the webhook.
+func (n *Notifier) waitFor(url string) time.Duration {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return time.Until(n.blocked[url])
+}
+
+// noteLimits remembers when an exhausted rate-limit bucket resets.
+func (n *Notifier) noteLimits(url string, h http.Header) {
+	if h.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+	secs, err := strconv.ParseFloat(h.Get("X-RateLimit-Reset-After"), 64)
+	if err != nil {
+		return
+	}
+	n.block(url, time.Duration(secs*float64(time.Second)))
+}
+
+// block holds posts to the webhook back for d.
+func (n *Notifier) block(url string, d time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.blocked[url] = time.Now().Add(d)
+}
+
+// retryAfter reads a 429's wait from its JSON retry_after (seconds, possibly
+// fractional), falling back to the Retry-After header, then to one second.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return 0
+	}
+	var body struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if json.Unmarshal(data, &body) == nil && body.RetryAfter > 0 {
+		return time.Duration(body.RetryAfter * float64(time.Second))
+	}
+	if secs, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	return time.Second
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,106 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+func newNotifier(url string) *Notifier {
+	return New(func() config.DiscordConfig {
+		return config.DiscordConfig{Webhooks: map[string]string{"family": url}, Username: "arp-notify"}
+	})
+}
+
+func TestSendPostsEmbedAfterRateLimit(t *testing.T) {
+	var calls int
+	var got payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 0.05, "global": false}`))
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	msg := notifier.Message{
+		Text: "Mom is home", Kind: config.TriggerArrival, Subject: "Mom", IP: "192.168.0.7",
+		Time: time.Date(2026, 3, 6, 18, 0, 0, 0, time.UTC),
+	}
+	n := newNotifier(srv.URL)
+	err := n.Send(context.Background(), "family", msg)
+	if err == nil || errors.Is(err, notifier.ErrPermanent) || calls != 1 {
+		t.Fatalf("want one retryable rate-limit failure, got %v after %d calls", err, calls)
+	}
+	// The outbox's retry waits out retry_after before posting again.
+	start := time.Now()
+	if err := n.Send(context.Background(), "family", msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if waited := time.Since(start); waited < 40*time.Millisecond {
+		t.Errorf("retry posted after %s, want it to wait for retry_after", waited)
+	}
+	if got.Username != "arp-notify" || len(got.Embeds) != 1 {
+		t.Fatalf("payload = %+v", got)
+	}
+	e := got.Embeds[0]
+	if e.Title != "Arrival" || e.Description != "Mom is home" || e.Timestamp != "2026-03-06T18:00:00Z" {
+		t.Errorf("embed = %+v", e)
+	}
+	var fields []string
+	for _, f := range e.Fields {
+		fields = append(fields, f.Name+"="+f.Value)
+	}
+	if want := "Who=Mom Time=<t:1772820000:f> IP=192.168.0.7"; strings.Join(fields, " ") != want {
+		t.Errorf("fields = %v, want %s", fields, want)
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Unknown Webhook"}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	n := newNotifier(srv.URL)
+	if err := n.Send(context.Background(), "family", notifier.Message{Text: "hi"}); err == nil || !strings.Contains(err.Error(), "404") || !errors.Is(err, notifier.ErrPermanent) {
+		t.Errorf("want a permanent 404 error, got %v", err)
+	}
+	if err := n.Send(context.Background(), "work", notifier.Message{Text: "hi"}); !errors.Is(err, notifier.ErrPermanent) {
+		t.Errorf("unknown webhook name: want a permanent error, got %v", err)
+	}
+}
+
+func TestSendWaitsForExhaustedBucket(t *testing.T) {
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset-After", "0.1")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	n := newNotifier(srv.URL)
+	for range 2 {
+		if err := n.Send(context.Background(), "family", notifier.Message{Text: "hi"}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if gap := times[1].Sub(times[0]); gap < 90*time.Millisecond {
+		t.Errorf("second post came %s after the first, want it to wait for the bucket reset", gap)
+	}
+}
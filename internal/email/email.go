@@ -0,0 +1,166 @@
+// Package email delivers notifications over SMTP, for receivers who don't use
+// a chat app.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+const dialTimeout = 15 * time.Second
+
+// Notifier sends mail through the SMTP server in config.yaml. It reads the
+// config on every send, so changes apply without a restart.
+type Notifier struct {
+	config   func() config.EmailConfig
+	password func() string
+
+	// tlsConfig is used for STARTTLS and implicit TLS; tests swap it to trust
+	// their own server.
+	tlsConfig func(host string) *tls.Config
+}
+
+// New returns a notifier reading its settings from cfg and the password from
+// SMTP_PASSWORD.
+func New(cfg func() config.EmailConfig) *Notifier {
+	return &Notifier{
+		config:    cfg,
+		password:  func() string { return os.Getenv("SMTP_PASSWORD") },
+		tlsConfig: func(host string) *tls.Config { return &tls.Config{ServerName: host} },
+	}
+}
+
+// Configured reports whether an SMTP server is set.
+func (n *Notifier) Configured() bool {
+	return n.config().Host != ""
+}
+
+// Send mails the message to the receiver address: a recipient list name or an
+// email address.
+func (n *Notifier) Send(ctx context.Context, address string, msg notifier.Message) error {
+	cfg := n.config()
+	if cfg.Host == "" {
+		return fmt.Errorf("email is not configured (email.host in config.yaml) (%w)", notifier.ErrPermanent)
+	}
+	to, err := cfg.Recipients(address)
+	if err != nil {
+		return fmt.Errorf("%w (%w)", err, notifier.ErrPermanent)
+	}
+	data, err := compose(cfg, to, msg)
+	if err != nil {
+		return err
+	}
+	return n.deliver(ctx, cfg, to, data)
+}
+
+// compose renders the templates into an RFC 5322 message.
+func compose(cfg config.EmailConfig, to []string, msg notifier.Message) ([]byte, error) {
+	subjectTmpl, bodyTmpl, err := cfg.Templates()
+	if err != nil {
+		return nil, err
+	}
+	var subject, body bytes.Buffer
+	if err := subjectTmpl.Execute(&subject, msg); err != nil {
+		return nil, fmt.Errorf("email subject: %w", err)
+	}
+	if err := bodyTmpl.Execute(&body, msg); err != nil {
+		return nil, fmt.Errorf("email body: %w", err)
+	}
+
+	date := msg.Time
+	if date.IsZero() {
+		date = time.Now()
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	// A subject must stay on one line; templates may produce several.
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject.String()), " ")))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body.String(), "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes(), nil
+}
+
+// deliver runs one SMTP transaction.
+func (n *Notifier) deliver(ctx context.Context, cfg config.EmailConfig, to []string, data []byte) error {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.ServerPort()))
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	var err error
+	if cfg.Security() == config.EmailTLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: n.tlsConfig(cfg.Host)}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("smtp %s: %w", addr, err)
+	}
+	// Bound the whole conversation, not just the dial.
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(time.Minute)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp %s: %w", addr, err)
+	}
+	defer c.Close()
+
+	if cfg.Security() == config.EmailTLSStartTLS {
+		if err := c.StartTLS(n.tlsConfig(cfg.Host)); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, n.password(), cfg.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return fmt.Errorf("email.from: %w", err)
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	for _, rcpt := range to {
+		a, err := mail.ParseAddress(rcpt)
+		if err != nil {
+			return fmt.Errorf("recipient %q: %w", rcpt, err)
+		}
+		if err := c.Rcpt(a.Address); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s: %w", a.Address, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	return c.Quit()
+}
@@ -0,0 +1,184 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+// fakeSMTP is a minimal in-process SMTP server that records one transaction.
+type fakeSMTP struct {
+	ln net.Listener
+
+	mu   sync.Mutex
+	auth string // decoded AUTH PLAIN credentials
+	from string
+	rcpt []string
+	data string
+}
+
+func startFakeSMTP(t *testing.T, ln net.Listener) *fakeSMTP {
+	s := &fakeSMTP{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		s.mu.Lock()
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-fake")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			s.auth = string(creds)
+			reply("235 ok")
+		case "MAIL":
+			s.from = arg
+			reply("250 ok")
+		case "RCPT":
+			s.rcpt = append(s.rcpt, arg)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			s.data = b.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			s.mu.Unlock()
+			return
+		default:
+			reply("502 not implemented")
+		}
+		s.mu.Unlock()
+	}
+}
+
+var mom = notifier.Message{
+	Text: "Mom is home", Kind: config.TriggerArrival, Subject: "Mom",
+	Time: time.Date(2026, 3, 6, 18, 0, 0, 0, time.UTC),
+}
+
+func TestSendPlainWithAuth(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startFakeSMTP(t, ln)
+
+	n := New(func() config.EmailConfig {
+		return config.EmailConfig{
+			Host: "127.0.0.1", Port: srv.port(), TLS: config.EmailTLSNone,
+			Username: "home", From: "arp-notify <home@example.com>",
+			To:      map[string][]string{"grandparents": {"grandma@example.com", "Grandpa <grandpa@example.com>"}},
+			Subject: "{{.Subject}} arrived",
+			Body:    "{{.Text}} at {{.Time.Format \"15:04\"}}.",
+		}
+	})
+	n.password = func() string { return "pw" }
+
+	if err := n.Send(context.Background(), "grandparents", mom); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.auth != "\x00home\x00pw" {
+		t.Errorf("auth = %q", srv.auth)
+	}
+	if srv.from != "FROM:<home@example.com>" || strings.Join(srv.rcpt, " ") != "TO:<grandma@example.com> TO:<grandpa@example.com>" {
+		t.Errorf("envelope from %q to %v", srv.from, srv.rcpt)
+	}
+	for _, want := range []string{"Subject: Mom arrived\r\n", "To: grandma@example.com, Grandpa <grandpa@example.com>\r\n", "\r\n\r\nMom is home at 18:00."} {
+		if !strings.Contains(srv.data, want) {
+			t.Errorf("message lacks %q:\n%s", want, srv.data)
+		}
+	}
+}
+
+func TestSendImplicitTLS(t *testing.T) {
+	// Borrow httptest's certificate, valid for 127.0.0.1.
+	hs := httptest.NewTLSServer(http.NotFoundHandler())
+	defer hs.Close()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: hs.TLS.Certificates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startFakeSMTP(t, ln)
+
+	n := New(func() config.EmailConfig {
+		return config.EmailConfig{Host: "127.0.0.1", Port: srv.port(), TLS: config.EmailTLSImplicit, From: "home@example.com"}
+	})
+	n.tlsConfig = func(string) *tls.Config {
+		return hs.Client().Transport.(*http.Transport).TLSClientConfig
+	}
+
+	if err := n.Send(context.Background(), "grandma@example.com", mom); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !strings.Contains(srv.data, "Subject: arp-notify: Mom is home\r\n") {
+		t.Errorf("default subject missing:\n%s", srv.data)
+	}
+}
+
+func TestSendUnknownList(t *testing.T) {
+	n := New(func() config.EmailConfig {
+		return config.EmailConfig{Host: "127.0.0.1", Port: 1, From: "home@example.com"}
+	})
+	if err := n.Send(context.Background(), "cousins", mom); err == nil || !strings.Contains(err.Error(), "cousins") || !errors.Is(err, notifier.ErrPermanent) {
+		t.Errorf("want an unknown list error, got %v", err)
+	}
+}
+
+func TestServerPortDefaults(t *testing.T) {
+	for tls, want := range map[string]int{"": 587, config.EmailTLSImplicit: 465, config.EmailTLSNone: 25} {
+		if got := (config.EmailConfig{TLS: tls}).ServerPort(); got != want {
+			t.Errorf("tls %q: port %d, want %d", tls, got, want)
+		}
+	}
+}
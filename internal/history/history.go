@@ -0,0 +1,158 @@
+// Package history keeps a persistent record of when each target and person
+// was home, and derives time-at-home statistics from it.
+package history
+
+import (
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/statefile"
+)
+
+const (
+	historyPath = "history.json"
+	retention   = 90 * 24 * time.Hour // visits that ended earlier are dropped
+)
+
+// Subject kinds.
+const (
+	KindTarget = "target"
+	KindPerson = "person"
+)
+
+// Visit is one stretch of time at home.
+type Visit struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`            // last seen home
+	Open  bool      `json:"open,omitempty"` // still home
+}
+
+// Subject is the visit history of a target or a person.
+type Subject struct {
+	Kind   string  `json:"kind"`
+	ID     string  `json:"id"` // MAC for targets, name for people
+	Name   string  `json:"name"`
+	Visits []Visit `json:"visits"` // oldest first
+}
+
+// Observation is one subject's presence as of a scan cycle.
+type Observation struct {
+	Kind string
+	ID   string
+	Name string
+	Home bool
+	At   time.Time // when the subject was last seen home
+}
+
+var (
+	mu       sync.Mutex
+	loaded   bool
+	subjects = make(map[string]*Subject) // kind:id -> subject
+)
+
+func key(kind, id string) string { return kind + ":" + id }
+
+// loadLocked reads the history file on first use. Callers must hold mu.
+func loadLocked() {
+	if loaded {
+		return
+	}
+	loaded = true
+	var stored []Subject
+	if _, err := statefile.Load(historyPath, &stored); err != nil {
+		log.Printf("Error loading history, starting empty: %v", err)
+		return
+	}
+	for _, s := range stored {
+		subjects[key(s.Kind, s.ID)] = &s
+	}
+}
+
+func saveLocked() {
+	out := make([]Subject, 0, len(subjects))
+	for _, s := range subjects {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return key(out[i].Kind, out[i].ID) < key(out[j].Kind, out[j].ID) })
+	if err := statefile.Save(historyPath, out); err != nil {
+		log.Printf("Error saving history: %v", err)
+	}
+}
+
+// Record merges one scan cycle's observations into the history and persists
+// it. A subject seen home extends its open visit or starts a new one; a
+// subject no longer home closes its open visit at the last sighting.
+func Record(obs []Observation, now time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+	loadLocked()
+
+	changed := false
+	for _, o := range obs {
+		k := key(o.Kind, o.ID)
+		s, ok := subjects[k]
+		if !ok {
+			if !o.Home {
+				continue
+			}
+			s = &Subject{Kind: o.Kind, ID: o.ID}
+			subjects[k] = s
+		}
+		if s.Name != o.Name {
+			s.Name, changed = o.Name, true
+		}
+
+		n := len(s.Visits)
+		open := n > 0 && s.Visits[n-1].Open
+		switch {
+		case o.Home && open:
+			if o.At.After(s.Visits[n-1].End) {
+				s.Visits[n-1].End, changed = o.At, true
+			}
+		case o.Home:
+			s.Visits, changed = append(s.Visits, Visit{Start: o.At, End: o.At, Open: true}), true
+		case open:
+			s.Visits[n-1].Open, changed = false, true
+		}
+	}
+
+	cutoff := now.Add(-retention)
+	for k, s := range subjects {
+		before := len(s.Visits)
+		s.Visits = slices.DeleteFunc(s.Visits, func(v Visit) bool { return !v.Open && v.End.Before(cutoff) })
+		if len(s.Visits) != before {
+			changed = true
+		}
+		if len(s.Visits) == 0 {
+			delete(subjects, k)
+		}
+	}
+
+	if changed {
+		saveLocked()
+	}
+}
+
+// Subjects returns a copy of the recorded history.
+func Subjects() []Subject {
+	mu.Lock()
+	defer mu.Unlock()
+	loadLocked()
+
+	out := make([]Subject, 0, len(subjects))
+	for _, s := range subjects {
+		c := *s
+		c.Visits = slices.Clone(s.Visits)
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind == KindPerson // people first
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
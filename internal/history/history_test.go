@@ -0,0 +1,104 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func resetHistory(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	mu.Lock()
+	defer mu.Unlock()
+	loaded = false
+	subjects = make(map[string]*Subject)
+}
+
+func obs(home bool, at time.Time) []Observation {
+	return []Observation{{Kind: KindPerson, ID: "Amy", Name: "Amy", Home: home, At: at}}
+}
+
+func TestRecordVisits(t *testing.T) {
+	resetHistory(t)
+	t0 := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+
+	Record(obs(false, time.Time{}), t0) // never home: nothing recorded
+	if got := Subjects(); len(got) != 0 {
+		t.Fatalf("away subject recorded: %+v", got)
+	}
+
+	Record(obs(true, t0), t0)
+	Record(obs(true, t0.Add(time.Hour)), t0.Add(time.Hour))
+	Record(obs(false, t0.Add(time.Hour)), t0.Add(2*time.Hour))
+	Record(obs(true, t0.Add(5*time.Hour)), t0.Add(5*time.Hour))
+
+	// Reload from disk.
+	mu.Lock()
+	loaded, subjects = false, make(map[string]*Subject)
+	mu.Unlock()
+
+	got := Subjects()
+	if len(got) != 1 || len(got[0].Visits) != 2 {
+		t.Fatalf("want one subject with two visits, got %+v", got)
+	}
+	v := got[0].Visits
+	if !v[0].Start.Equal(t0) || !v[0].End.Equal(t0.Add(time.Hour)) || v[0].Open {
+		t.Errorf("first visit = %+v", v[0])
+	}
+	if !v[1].Open {
+		t.Errorf("second visit should still be open: %+v", v[1])
+	}
+}
+
+func TestRecordDropsOldVisits(t *testing.T) {
+	resetHistory(t)
+	t0 := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	Record(obs(true, t0), t0)
+	Record(obs(false, t0), t0.Add(time.Hour))
+	Record(nil, t0.Add(retention+2*time.Hour))
+	if got := Subjects(); len(got) != 0 {
+		t.Errorf("expired history kept: %+v", got)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	loc := time.UTC
+	day := func(d, h, m int) time.Time { return time.Date(2026, 3, d, h, m, 0, 0, loc) }
+	now := day(10, 12, 0)
+
+	s := Subject{Kind: KindPerson, ID: "Amy", Name: "Amy", Visits: []Visit{
+		{Start: day(5, 18, 0), End: day(6, 8, 0)},  // 6h on the 5th, 8h on the 6th
+		{Start: day(6, 19, 0), End: day(7, 8, 30)}, // 5h on the 6th, 8.5h on the 7th
+		{Start: day(10, 9, 0), End: now, Open: true},
+	}}
+	st := Compute(s, now, loc)
+
+	if !st.Home || st.CurrentAwayMin != 0 {
+		t.Errorf("home = %v, currentAway = %d", st.Home, st.CurrentAwayMin)
+	}
+	if st.TodayMin != 180 {
+		t.Errorf("todayMin = %d, want 180", st.TodayMin)
+	}
+	if want := 360 + 480 + 300 + 510 + 180; st.WeekMin != want || st.MonthMin != want {
+		t.Errorf("weekMin = %d, monthMin = %d, want %d", st.WeekMin, st.MonthMin, want)
+	}
+	if len(st.Daily) != statsDays || st.Daily[statsDays-1].Date != "2026-03-10" {
+		t.Errorf("daily series ends on %q", st.Daily[len(st.Daily)-1].Date)
+	}
+	if st.UsualArrival != "18:00" || st.UsualDeparture != "08:30" {
+		t.Errorf("usual arrival/departure = %s/%s", st.UsualArrival, st.UsualDeparture)
+	}
+	if want := int(day(10, 9, 0).Sub(day(7, 8, 30)) / time.Minute); st.LongestAwayMin != want {
+		t.Errorf("longestAwayMin = %d, want %d", st.LongestAwayMin, want)
+	}
+	if st.AwayDays != 2 { // the 8th and the 9th
+		t.Errorf("awayDays = %d, want 2", st.AwayDays)
+	}
+
+	s.Visits = s.Visits[:2]
+	st = Compute(s, now, loc)
+	if st.Home || st.CurrentAwayMin != int(now.Sub(day(7, 8, 30))/time.Minute) {
+		t.Errorf("away: home = %v, currentAway = %d", st.Home, st.CurrentAwayMin)
+	}
+}
@@ -0,0 +1,136 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// statsDays is how many days the daily series and the usual times cover.
+const statsDays = 30
+
+// Day is the time at home on one calendar day.
+type Day struct {
+	Date    string `json:"date"` // YYYY-MM-DD
+	Minutes int    `json:"minutes"`
+}
+
+// Stats summarizes a subject's time at home. Periods are rolling: today since
+// midnight, the last 7 days and the last 30 days, both including today.
+type Stats struct {
+	Kind           string `json:"kind"`
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Home           bool   `json:"home"`
+	TodayMin       int    `json:"todayMin"`
+	WeekMin        int    `json:"weekMin"`
+	MonthMin       int    `json:"monthMin"`
+	Daily          []Day  `json:"daily"`                    // oldest first
+	UsualArrival   string `json:"usualArrival,omitempty"`   // HH:MM, median over 30 days
+	UsualDeparture string `json:"usualDeparture,omitempty"` // HH:MM, median over 30 days
+	CurrentAwayMin int    `json:"currentAwayMin"`           // 0 while home
+	LongestAwayMin int    `json:"longestAwayMin"`           // longest absence within 30 days
+	AwayDays       int    `json:"awayDays"`                 // full days in a row without any time at home, up to yesterday
+}
+
+// Compute derives the statistics of a subject as of now, with calendar days
+// in loc.
+func Compute(s Subject, now time.Time, loc *time.Location) Stats {
+	st := Stats{Kind: s.Kind, ID: s.ID, Name: s.Name}
+	visits := make([]Visit, len(s.Visits))
+	for i, v := range s.Visits {
+		if v.Open {
+			v.End = now
+			st.Home = true
+		}
+		visits[i] = v
+	}
+
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	windowStart := today.AddDate(0, 0, -(statsDays - 1))
+
+	for i := statsDays - 1; i >= 0; i-- {
+		start := today.AddDate(0, 0, -i)
+		end := start.AddDate(0, 0, 1)
+		if end.After(now) {
+			end = now
+		}
+		minutes := int(overlap(visits, start, end) / time.Minute)
+		st.Daily = append(st.Daily, Day{Date: start.Format(time.DateOnly), Minutes: minutes})
+		st.MonthMin += minutes
+		if i < 7 {
+			st.WeekMin += minutes
+		}
+		if i == 0 {
+			st.TodayMin = minutes
+		}
+	}
+
+	// Days before the first recorded visit are unknown, not away.
+	if len(visits) > 0 {
+		for i := statsDays - 2; i >= 0 && st.Daily[i].Minutes == 0; i-- {
+			if dayEnd := today.AddDate(0, 0, i-statsDays+2); dayEnd.Before(visits[0].Start) {
+				break
+			}
+			st.AwayDays++
+		}
+	}
+
+	var arrivals, departures []int
+	var prevEnd time.Time
+	awaySince := func(from, until time.Time) int {
+		if from.Before(windowStart) {
+			from = windowStart
+		}
+		return int(until.Sub(from) / time.Minute)
+	}
+	for _, v := range visits {
+		if !v.Start.Before(windowStart) {
+			arrivals = append(arrivals, minuteOfDay(v.Start.In(loc)))
+		}
+		if !v.Open && !v.End.Before(windowStart) {
+			departures = append(departures, minuteOfDay(v.End.In(loc)))
+		}
+		if !prevEnd.IsZero() && v.Start.After(windowStart) {
+			st.LongestAwayMin = max(st.LongestAwayMin, awaySince(prevEnd, v.Start))
+		}
+		prevEnd = v.End
+	}
+	if !st.Home && !prevEnd.IsZero() {
+		st.CurrentAwayMin = int(now.Sub(prevEnd) / time.Minute)
+		st.LongestAwayMin = max(st.LongestAwayMin, awaySince(prevEnd, now))
+	}
+	st.UsualArrival = medianClock(arrivals)
+	st.UsualDeparture = medianClock(departures)
+	return st
+}
+
+// overlap returns how much of [start, end) the visits cover.
+func overlap(visits []Visit, start, end time.Time) time.Duration {
+	var total time.Duration
+	for _, v := range visits {
+		s, e := v.Start, v.End
+		if s.Before(start) {
+			s = start
+		}
+		if e.After(end) {
+			e = end
+		}
+		if e.After(s) {
+			total += e.Sub(s)
+		}
+	}
+	return total
+}
+
+func minuteOfDay(t time.Time) int { return t.Hour()*60 + t.Minute() }
+
+func medianClock(minutes []int) string {
+	if len(minutes) == 0 {
+		return ""
+	}
+	sort.Ints(minutes)
+	m := minutes[len(minutes)/2]
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
@@ -0,0 +1,142 @@
+// Package inventory keeps a persistent record of every host seen on the LAN
+// by the broadcast scan.
+package inventory
+
+import (
+	"context"
+	"log"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/arpscan"
+	"github.com/nekogravitycat/arp-notify/internal/statefile"
+)
+
+const (
+	inventoryPath = "inventory.json"
+	maxIPs        = 10 // most recent IPs kept per device
+	lookupTimeout = 2 * time.Second
+)
+
+// Device is one host of the LAN inventory.
+type Device struct {
+	Mac       string    `json:"mac"`
+	IPs       []string  `json:"ips"` // most recent first
+	Vendor    string    `json:"vendor"`
+	Hostname  string    `json:"hostname"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+var (
+	mu      sync.Mutex
+	loaded  bool
+	devices = make(map[string]Device) // lower-cased MAC -> device
+)
+
+// lookupHostname resolves an IP to a hostname via reverse DNS. It is a
+// variable so tests can stub it out.
+var lookupHostname = func(ip string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
+
+// loadLocked reads the inventory file on first use. Callers must hold mu.
+func loadLocked() {
+	if loaded {
+		return
+	}
+	loaded = true
+	var stored []Device
+	if _, err := statefile.Load(inventoryPath, &stored); err != nil {
+		log.Printf("Error loading inventory, starting empty: %v", err)
+		return
+	}
+	for _, d := range stored {
+		devices[d.Mac] = d
+	}
+}
+
+func saveLocked() {
+	if err := statefile.Save(inventoryPath, sortedLocked()); err != nil {
+		log.Printf("Error saving inventory: %v", err)
+	}
+}
+
+func sortedLocked() []Device {
+	out := make([]Device, 0, len(devices))
+	for _, d := range devices {
+		d.IPs = slices.Clone(d.IPs)
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeen.After(out[j].LastSeen) })
+	return out
+}
+
+// Record merges the hosts of one broadcast scan into the inventory and
+// persists it. With resolve set, IPs not seen before for a device are looked
+// up via reverse DNS in the background to fill in its hostname.
+func Record(hosts []arpscan.Host, now time.Time, resolve bool) {
+	if len(hosts) == 0 {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	loadLocked()
+
+	for _, h := range hosts {
+		d, ok := devices[h.MAC]
+		if !ok {
+			d = Device{Mac: h.MAC, FirstSeen: now}
+		}
+		d.LastSeen = now
+		if h.Vendor != "" {
+			d.Vendor = h.Vendor
+		}
+		if len(d.IPs) == 0 || d.IPs[0] != h.IP {
+			isNew := !slices.Contains(d.IPs, h.IP)
+			d.IPs = append([]string{h.IP}, slices.DeleteFunc(d.IPs, func(ip string) bool { return ip == h.IP })...)
+			if len(d.IPs) > maxIPs {
+				d.IPs = d.IPs[:maxIPs]
+			}
+			if isNew && resolve {
+				go resolveHostname(h.MAC, h.IP)
+			}
+		}
+		devices[h.MAC] = d
+	}
+	saveLocked()
+}
+
+func resolveHostname(mac, ip string) {
+	name := lookupHostname(ip)
+	if name == "" {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if d, ok := devices[mac]; ok && d.Hostname != name {
+		d.Hostname = name
+		devices[mac] = d
+		saveLocked()
+	}
+}
+
+// Devices returns the inventory, most recently seen first.
+func Devices() []Device {
+	mu.Lock()
+	defer mu.Unlock()
+	loadLocked()
+	return sortedLocked()
+}
@@ -0,0 +1,95 @@
+package inventory
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/arpscan"
+)
+
+func resetInventory(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	mu.Lock()
+	defer mu.Unlock()
+	loaded = false
+	devices = make(map[string]Device)
+}
+
+func TestRecordMergesSightings(t *testing.T) {
+	resetInventory(t)
+
+	first := time.Now().Add(-time.Hour)
+	Record([]arpscan.Host{{IP: "192.168.0.5", MAC: "aa:bb:cc:dd:ee:05", Vendor: "Acme"}}, first, false)
+
+	later := time.Now()
+	Record([]arpscan.Host{{IP: "192.168.0.6", MAC: "aa:bb:cc:dd:ee:05"}}, later, false)
+
+	got := Devices()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 device, got %d", len(got))
+	}
+	d := got[0]
+	if !d.FirstSeen.Equal(first) || !d.LastSeen.Equal(later) {
+		t.Errorf("first/last seen = %s/%s, want %s/%s", d.FirstSeen, d.LastSeen, first, later)
+	}
+	if len(d.IPs) != 2 || d.IPs[0] != "192.168.0.6" || d.IPs[1] != "192.168.0.5" {
+		t.Errorf("IPs = %v, want most recent first", d.IPs)
+	}
+	if d.Vendor != "Acme" {
+		t.Errorf("an empty vendor should not overwrite a known one, got %q", d.Vendor)
+	}
+}
+
+func TestRecordCapsIPs(t *testing.T) {
+	resetInventory(t)
+
+	now := time.Now()
+	for i := range maxIPs + 5 {
+		Record([]arpscan.Host{{IP: fmt.Sprintf("10.0.0.%d", i), MAC: "aa:bb:cc:dd:ee:05"}}, now, false)
+	}
+	if n := len(Devices()[0].IPs); n != maxIPs {
+		t.Errorf("kept %d IPs, want %d", n, maxIPs)
+	}
+}
+
+func TestInventoryPersists(t *testing.T) {
+	resetInventory(t)
+	Record([]arpscan.Host{{IP: "192.168.0.5", MAC: "aa:bb:cc:dd:ee:05"}}, time.Now(), false)
+
+	// Simulate a restart: drop the in-memory copy and reload from disk.
+	mu.Lock()
+	loaded = false
+	devices = make(map[string]Device)
+	mu.Unlock()
+
+	if got := Devices(); len(got) != 1 || got[0].Mac != "aa:bb:cc:dd:ee:05" {
+		t.Errorf("inventory not restored from disk: %+v", got)
+	}
+}
+
+func TestRecordResolvesHostname(t *testing.T) {
+	resetInventory(t)
+
+	done := make(chan struct{})
+	orig := lookupHostname
+	lookupHostname = func(ip string) string {
+		defer close(done)
+		return "printer.lan"
+	}
+	t.Cleanup(func() { lookupHostname = orig })
+
+	Record([]arpscan.Host{{IP: "192.168.0.5", MAC: "aa:bb:cc:dd:ee:05"}}, time.Now(), true)
+	<-done
+
+	// resolveHostname stores the name right after the lookup returns.
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if Devices()[0].Hostname == "printer.lan" {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("hostname was not recorded")
+}
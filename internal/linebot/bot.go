@@ -1,6 +1,7 @@
 package linebot
 
 import (
+	"context"
 	"errors"
 	"log"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
 	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
 )
 
 // Singleton instance of the LINE bot client
@@ -46,22 +48,28 @@ func getBot() (*messaging_api.MessagingApiAPI, error) {
 	return _bot, _botErr
 }
 
-func SendMessage(to string, message string) error {
-	bot, err := getBot()
+// push sends a push message, bounded by ctx. A non-empty retryKey (a UUID)
+// makes LINE accept the request only once: retrying a push that already went
+// through is answered with 409 Conflict, which counts as success.
+func push(ctx context.Context, to, retryKey string, messages ...messaging_api.MessageInterface) error {
+	shared, err := getBot()
 	if err != nil {
 		return err
 	}
+	// WithContext sets the context on the client itself, so use a copy.
+	bot := *shared
+	bot.WithContext(ctx)
 
-	_, err = bot.PushMessage(
+	res, _, err := bot.PushMessageWithHttpInfo(
 		&messaging_api.PushMessageRequest{
-			To: to,
-			Messages: []messaging_api.MessageInterface{
-				messaging_api.TextMessageV2{Text: message},
-			},
+			To:       to,
+			Messages: messages,
 		},
-		"", // x-line-retry-key
+		retryKey,
 	)
-
+	if err != nil && retryKey != "" && res != nil && res.StatusCode == http.StatusConflict {
+		return nil
+	}
 	return err
 }
 
@@ -92,22 +100,35 @@ func onCallback(writer http.ResponseWriter, request *http.Request) {
 	}
 }
 
-// onMessageEvent records the sender (for the web UI's receiver picker) and,
-// when the text is "whoami", replies with the user's ID.
+// CommandHandler answers a chat command. userID is the chat it came from: the
+// user's ID, or the group's or room's ID for commands sent in a group chat.
+// It returns the reply and whether the text was a command it handles.
+type CommandHandler func(userID, text string) (reply string, handled bool)
+
+var commandHandler CommandHandler
+
+// SetCommandHandler installs the handler for chat commands other than
+// "whoami". It lets the monitor be controlled from chat without this package
+// depending on it.
+func SetCommandHandler(h CommandHandler) {
+	commandHandler = h
+}
+
+// onMessageEvent records the chat (for the web UI's receiver picker) and
+// replies to commands: "whoami" with the chat's ID, anything else through the
+// installed command handler. In a group or multi-person chat that is the
+// group's or room's ID, so the whole chat can be configured as a receiver.
 func onMessageEvent(event webhook.MessageEvent) {
-	source, ok := event.Source.(webhook.UserSource)
-	if !ok {
+	chatId, kind := sourceID(event.Source)
+	if chatId == "" {
 		return
 	}
-	userId := source.UserId
 
-	// Remember the user; fetch the LINE profile display name once.
-	recordSeenUser(userId, "")
-	if seenUserNeedsName(userId) {
+	// Remember the chat; fetch its display name once.
+	seenUsers.Record(chatId, kind, "")
+	if seenUsers.NeedsName(chatId) {
 		if bot, err := getBot(); err == nil {
-			if profile, err := bot.GetProfile(userId); err == nil && profile != nil {
-				recordSeenUser(userId, profile.DisplayName)
-			}
+			seenUsers.Record(chatId, kind, displayName(bot, chatId, kind))
 		}
 	}
 
@@ -115,24 +136,73 @@ func onMessageEvent(event webhook.MessageEvent) {
 	if !ok {
 		return
 	}
+	reply := chatId
 	if message.Text != "whoami" {
-		return
+		if commandHandler == nil {
+			return
+		}
+		var handled bool
+		if reply, handled = commandHandler(chatId, message.Text); !handled {
+			return
+		}
 	}
 
 	bot, err := getBot()
 	if err != nil {
-		log.Printf("Cannot reply to %s: %v\n", userId, err)
+		log.Printf("Cannot reply to %s: %v\n", chatId, err)
 		return
 	}
 	_, err = bot.ReplyMessage(
 		&messaging_api.ReplyMessageRequest{
 			ReplyToken: event.ReplyToken,
 			Messages: []messaging_api.MessageInterface{
-				messaging_api.TextMessageV2{Text: userId},
+				messaging_api.TextMessageV2{Text: reply},
 			},
 		},
 	)
 	if err != nil {
-		log.Printf("Error replying message to %s: %v\n", userId, err)
+		log.Printf("Error replying message to %s: %v\n", chatId, err)
+	}
+}
+
+// sourceID returns the ID that addresses the chat an event came from and the
+// chat's kind, or "" for unknown sources.
+func sourceID(source webhook.SourceInterface) (id, kind string) {
+	switch s := source.(type) {
+	case webhook.UserSource:
+		return s.UserId, KindUser
+	case webhook.GroupSource:
+		return s.GroupId, KindGroup
+	case webhook.RoomSource:
+		return s.RoomId, KindRoom
+	}
+	return "", ""
+}
+
+// displayName looks up a user's profile name or a group's name. Multi-person
+// chats have no name, so they stay unnamed.
+func displayName(bot *messaging_api.MessagingApiAPI, id, kind string) string {
+	switch kind {
+	case KindUser:
+		if profile, err := bot.GetProfile(id); err == nil && profile != nil {
+			return profile.DisplayName
+		}
+	case KindGroup:
+		if summary, err := bot.GetGroupSummary(id); err == nil && summary != nil {
+			return summary.GroupName
+		}
+	}
+	return ""
+}
+
+// Notifier delivers messages as LINE push messages, for the notifier registry.
+type Notifier struct{}
+
+// Send pushes the message as text, or as a Flex Message card when the subject
+// has a LINE Flex layout. The message ID is sent as the retry key.
+func (Notifier) Send(ctx context.Context, to string, msg notifier.Message) error {
+	if msg.LineFlex != nil {
+		return push(ctx, to, msg.ID, flexMessage(msg, statusURL()))
 	}
+	return push(ctx, to, msg.ID, messaging_api.TextMessageV2{Text: msg.Text})
 }
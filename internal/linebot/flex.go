@@ -0,0 +1,125 @@
+package linebot
+
+import (
+	"strings"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+// maxAltText is LINE's limit on a Flex Message's altText.
+const maxAltText = 400
+
+// Header colors by event, matching the admin UI palette.
+var flexColors = map[string]string{
+	config.TriggerArrival:   "#3fb950",
+	config.TriggerDeparture: "#f0506e",
+}
+
+var flexTitles = map[string]string{
+	config.TriggerArrival:   "Arrived home",
+	config.TriggerDeparture: "Left home",
+}
+
+// flexMessage lays out an arrival or departure as a Flex Message card: a
+// colored header, the subject and the message, the configured detail rows and,
+// when statusURL is set, a button opening the admin status page. The plain
+// text stays the altText shown in chat lists and notifications.
+func flexMessage(msg notifier.Message, statusURL string) *messaging_api.FlexMessage {
+	layout := *msg.LineFlex
+	color := layout.Color
+	if color == "" {
+		color = flexColors[msg.Kind]
+	}
+	if color == "" {
+		color = "#4f8cff"
+	}
+	title := flexTitles[msg.Kind]
+	if title == "" {
+		title = "arp-notify"
+	}
+
+	body := []messaging_api.FlexComponentInterface{
+		&messaging_api.FlexText{Text: msg.Subject, Size: "xl", Weight: messaging_api.FlexTextWEIGHT_BOLD, Wrap: true},
+		&messaging_api.FlexText{Text: msg.Text, Size: "md", Color: "#555555", Wrap: true, Margin: "sm"},
+	}
+	var rows []messaging_api.FlexComponentInterface
+	for _, field := range layout.Rows() {
+		if label, value := flexRow(field, msg); value != "" {
+			rows = append(rows, &messaging_api.FlexBox{
+				Layout:  messaging_api.FlexBoxLAYOUT_BASELINE,
+				Spacing: "sm",
+				Contents: []messaging_api.FlexComponentInterface{
+					&messaging_api.FlexText{Text: label, Size: "sm", Color: "#aaaaaa", Flex: 2},
+					&messaging_api.FlexText{Text: value, Size: "sm", Color: "#666666", Flex: 5, Wrap: true},
+				},
+			})
+		}
+	}
+	if len(rows) > 0 {
+		body = append(body, &messaging_api.FlexBox{Layout: messaging_api.FlexBoxLAYOUT_VERTICAL, Margin: "lg", Spacing: "sm", Contents: rows})
+	}
+
+	bubble := &messaging_api.FlexBubble{
+		Header: &messaging_api.FlexBox{
+			Layout:          messaging_api.FlexBoxLAYOUT_VERTICAL,
+			BackgroundColor: color,
+			Contents: []messaging_api.FlexComponentInterface{
+				&messaging_api.FlexText{Text: title, Color: "#ffffff", Weight: messaging_api.FlexTextWEIGHT_BOLD},
+			},
+		},
+		Body: &messaging_api.FlexBox{Layout: messaging_api.FlexBoxLAYOUT_VERTICAL, Contents: body},
+	}
+	if statusURL != "" {
+		label := layout.ButtonLabel
+		if label == "" {
+			label = "Open status"
+		}
+		bubble.Footer = &messaging_api.FlexBox{
+			Layout: messaging_api.FlexBoxLAYOUT_VERTICAL,
+			Contents: []messaging_api.FlexComponentInterface{
+				&messaging_api.FlexButton{
+					Style:  messaging_api.FlexButtonSTYLE_LINK,
+					Action: &messaging_api.UriAction{Label: label, Uri: statusURL},
+				},
+			},
+		}
+	}
+
+	return &messaging_api.FlexMessage{AltText: altText(msg.Text), Contents: bubble}
+}
+
+// flexRow returns a detail row's label and value; an empty value skips it.
+func flexRow(field string, msg notifier.Message) (label, value string) {
+	switch field {
+	case config.FlexFieldTime:
+		if !msg.Time.IsZero() {
+			return "Time", msg.Time.Format("Mon 15:04")
+		}
+	case config.FlexFieldAwayFor:
+		if msg.AwayFor > 0 {
+			return "Away for", config.HumanDuration(msg.AwayFor)
+		}
+	case config.FlexFieldIP:
+		return "IP", msg.IP
+	case config.FlexFieldMac:
+		return "MAC", msg.Mac
+	}
+	return "", ""
+}
+
+func altText(text string) string {
+	if text = strings.TrimSpace(text); text == "" {
+		text = "arp-notify"
+	}
+	if r := []rune(text); len(r) > maxAltText {
+		text = string(r[:maxAltText-1]) + "…"
+	}
+	return text
+}
+
+// statusURL returns the admin status page, or "" without a public URL.
+func statusURL() string {
+	return config.GetSystemConfig().Server.Link("status")
+}
@@ -0,0 +1,63 @@
+package linebot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+func TestFlexMessage(t *testing.T) {
+	msg := notifier.Message{
+		Text:     "Welcome home, Amy!",
+		Kind:     config.TriggerArrival,
+		Subject:  "Amy",
+		IP:       "192.168.0.7",
+		Time:     time.Date(2026, 3, 6, 18, 5, 0, 0, time.UTC),
+		AwayFor:  26*time.Hour + 10*time.Minute,
+		LineFlex: &config.LineFlex{Enabled: true, Fields: []string{"time", "away_for", "ip", "mac"}},
+	}
+	data, err := json.Marshal(flexMessage(msg, "https://home.example.com/#status"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		`"type":"flex"`,
+		`"altText":"Welcome home, Amy!"`,
+		`"backgroundColor":"#3fb950"`,
+		`"text":"Arrived home"`,
+		`"text":"Fri 18:05"`,
+		`"text":"1d 2h"`,
+		`"text":"192.168.0.7"`,
+		`"uri":"https://home.example.com/#status"`,
+		`"label":"Open status"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("flex message lacks %s:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `"text":"MAC"`) {
+		t.Error("empty MAC row should be skipped")
+	}
+}
+
+func TestFlexMessageWithoutButton(t *testing.T) {
+	msg := notifier.Message{
+		Text:     strings.Repeat("x", 500),
+		Kind:     config.TriggerDeparture,
+		Subject:  "Dad",
+		LineFlex: &config.LineFlex{Enabled: true, Color: "#123456"},
+	}
+	fm := flexMessage(msg, "")
+	if n := len([]rune(fm.AltText)); n != maxAltText {
+		t.Errorf("altText has %d characters, want %d", n, maxAltText)
+	}
+	data, _ := json.Marshal(fm)
+	if got := string(data); strings.Contains(got, `"footer"`) || !strings.Contains(got, `"backgroundColor":"#123456"`) {
+		t.Errorf("flex message = %s", got)
+	}
+}
@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+	"github.com/nekogravitycat/arp-notify/internal/outbox"
+)
+
+// pendingBatch collects the arrivals announced to one receiver during the
+// batching window. The merged message waits in the outbox as entry id until
+// the window closes, so a restart does not lose it.
+type pendingBatch struct {
+	batching config.Batching
+	id       string
+	until    time.Time // when the window closes
+	messages []notifier.Message
+}
+
+var (
+	batchMu sync.Mutex
+	batches = make(map[string]*pendingBatch) // receiver ID -> pending arrivals
+)
+
+// notifyArrival is notifyAbout for an arrival. With batching enabled,
+// messages that would go out right away are held for the batching window and
+// merged with the other arrivals announced to the same receiver meanwhile.
+func notifyArrival(batching config.Batching, about notifier.Message, receivers []config.Receiver, quiet config.Schedule, messageFor func(config.Receiver) string) bool {
+	about.Kind = config.TriggerArrival
+	if batching.Window() <= 0 || (len(receivers) > 0 && Paused()) {
+		return notifyAbout(about, receivers, quiet, messageFor)
+	}
+	return deliverVia(about, receivers, quiet, messageFor, func(receiverID string, msg notifier.Message) {
+		queueArrival(batching, receiverID, msg)
+	})
+}
+
+// queueArrival adds an arrival to the receiver's batch, starting the batching
+// window if there is no open batch. The batch goes out when its outbox entry
+// comes due.
+func queueArrival(batching config.Batching, receiverID string, msg notifier.Message) {
+	batchMu.Lock()
+	defer batchMu.Unlock()
+
+	now := time.Now()
+	if b, ok := batches[receiverID]; ok && now.Before(b.until) {
+		b.messages = append(b.messages, msg)
+		if outbox.Update(b.id, b.merged(receiverID)) {
+			return
+		}
+		// The batch is already on its way; start anew.
+	}
+	until := now.Add(batching.Window())
+	batches[receiverID] = &pendingBatch{
+		batching: batching,
+		id:       outbox.EnqueueAt(receiverID, msg, until),
+		until:    until,
+		messages: []notifier.Message{msg},
+	}
+}
+
+// merged returns the message to send for the batch: the arrival's own message
+// when it is alone, the batching template otherwise.
+func (b *pendingBatch) merged(receiverID string) notifier.Message {
+	if len(b.messages) == 1 {
+		return b.messages[0]
+	}
+
+	data := config.BatchData{Count: len(b.messages)}
+	for _, m := range b.messages {
+		data.Names = append(data.Names, m.Subject)
+		data.Messages = append(data.Messages, m.Text)
+	}
+	log.Printf("Batching: merged %d arrivals for %s.", data.Count, receiverID)
+
+	var buf bytes.Buffer
+	tmpl, err := b.batching.Template()
+	if err == nil {
+		err = tmpl.Execute(&buf, data)
+	}
+	if err != nil {
+		// The template was test-rendered on load; fall back to the default wording.
+		log.Printf("Batching: invalid message template: %v", err)
+		buf.Reset()
+		tmpl, _ = config.Batching{}.Template()
+		_ = tmpl.Execute(&buf, data)
+	}
+	return notifier.Message{
+		Text:    buf.String(),
+		Kind:    config.TriggerArrival,
+		Subject: strings.Join(data.Names, ", "),
+		Time:    b.messages[0].Time,
+	}
+}
@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+func resetBatches(t *testing.T) {
+	t.Chdir(t.TempDir()) // batches wait in the outbox file
+	batchMu.Lock()
+	defer batchMu.Unlock()
+	batches = make(map[string]*pendingBatch)
+}
+
+func arrival(name, text string) notifier.Message {
+	return notifier.Message{Text: text, Kind: config.TriggerArrival, Subject: name}
+}
+
+// hourBatching keeps the window open for the whole test.
+var hourBatching = config.Batching{WindowSec: 3600}
+
+// batchFor returns the one message waiting in the outbox for a receiver.
+func batchFor(t *testing.T, receiverID string) notifier.Message {
+	t.Helper()
+	q := queued(receiverID)
+	if len(q) != 1 {
+		t.Fatalf("want one queued message for %s, got %+v", receiverID, q)
+	}
+	return q[0].Message
+}
+
+func TestBatchMergesArrivals(t *testing.T) {
+	resetBatches(t)
+
+	queueArrival(hourBatching, "merge-1", arrival("Mom", "Welcome home!"))
+	queueArrival(hourBatching, "merge-1", arrival("Dad", "Welcome home!"))
+	queueArrival(hourBatching, "merge-1", arrival("Amy", "Hi Amy"))
+	queueArrival(hourBatching, "merge-2", arrival("Amy", "Amy is back"))
+
+	if got := batchFor(t, "merge-1"); got.Text != "Mom, Dad and Amy are home" || got.Subject != "Mom, Dad, Amy" {
+		t.Errorf("merge-1 got %+v", got)
+	}
+	// A lone arrival keeps its own message.
+	if got := batchFor(t, "merge-2"); got.Text != "Amy is back" {
+		t.Errorf("merge-2 got %+v", got)
+	}
+	if e := queued("merge-1")[0]; e.Deferred || !e.NextAttempt.After(e.Created) {
+		t.Errorf("batch should wait for its window, got %+v", e)
+	}
+}
+
+func TestBatchCustomTemplate(t *testing.T) {
+	resetBatches(t)
+
+	b := config.Batching{WindowSec: 3600, Message: "{{.Count}} arrived: {{join .Names}}"}
+	queueArrival(b, "custom-1", arrival("Mom", "m"))
+	queueArrival(b, "custom-1", arrival("Dad", "d"))
+	if got := batchFor(t, "custom-1"); got.Text != "2 arrived: Mom and Dad" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestNotifyArrivalBatchesOnlyWhenEnabled(t *testing.T) {
+	resetBatches(t)
+	resetPause()
+
+	message := func(config.Receiver) string { return "hi" }
+
+	notifyArrival(config.Batching{}, notifier.Message{Subject: "Mom"}, []config.Receiver{{ID: "unbatched-1"}}, config.Schedule{}, message)
+	if e := queued("unbatched-1"); len(e) != 1 || e[0].NextAttempt.After(e[0].Created) {
+		t.Errorf("arrival held back with batching disabled: %+v", e)
+	}
+
+	notifyArrival(hourBatching, notifier.Message{Subject: "Mom"}, []config.Receiver{{ID: "batched-1"}}, config.Schedule{}, message)
+	if got := batchFor(t, "batched-1"); got.Text != "hi" || got.Kind != config.TriggerArrival {
+		t.Errorf("batched arrival = %+v", got)
+	}
+}
@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+// HandleChatCommand answers the monitor's chat commands:
+//
+//	pause [duration] [reason]   e.g. "pause 3d family trip"; no duration = until resumed
+//	resume
+//
+// userID is the chat's receiver ID, a bare LINE user, group or room ID or e.g.
+// "telegram:123". Only chats configured as a receiver somewhere may use the
+// commands, since anyone can message the bot.
+func HandleChatCommand(userID, text string) (string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", false
+	}
+	cmd := strings.ToLower(fields[0])
+	if cmd != "pause" && cmd != "resume" {
+		return "", false
+	}
+	if !isReceiver(config.GetTargetsConfig(), userID) {
+		return "Only configured receivers can control monitoring.", true
+	}
+
+	if cmd == "resume" {
+		Resume()
+		return "Notifications resumed.", true
+	}
+
+	var until time.Time
+	args := fields[1:]
+	if len(args) > 0 {
+		if d, ok := parseChatDuration(args[0]); ok {
+			until = time.Now().Add(d)
+			args = args[1:]
+		}
+	}
+	if err := Pause(until, strings.Join(args, " ")); err != nil {
+		return "Cannot pause: " + err.Error(), true
+	}
+	return "Notifications " + CurrentPause().String() + ".", true
+}
+
+// parseChatDuration accepts Go durations ("90m", "12h") and whole days ("3d").
+func parseChatDuration(s string) (time.Duration, bool) {
+	if days, ok := strings.CutSuffix(strings.ToLower(s), "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, false
+		}
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// isReceiver reports whether the ID receives any notification in the config.
+// A bare chat user ID matches the same user written with its channel prefix.
+func isReceiver(cfg config.TargetsConfig, id string) bool {
+	for _, r := range cfg.AllReceivers() {
+		if config.SameReceiver(r.ID, id) {
+			return true
+		}
+	}
+	return false
+}
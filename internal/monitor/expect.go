@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+// lastExpectCheck is when expectations were last evaluated; windows that
+// closed after it are due. Guarded by stateMu.
+var lastExpectCheck time.Time
+
+// expectEvent is a target missing an expected arrival, or away for too long.
+type expectEvent struct {
+	target  config.Target
+	message string
+}
+
+// checkExpectations runs on the scan loop's clock. It reports every enabled
+// target whose expected-arrival window closed since the previous check without
+// a sighting, and every target that has just been away for away_alert_hours.
+// The first check after startup only sets the clock, so windows that closed
+// while the service was down are not reported late. Snoozed targets are
+// skipped.
+func checkExpectations(targetsCfg config.TargetsConfig, now time.Time) []expectEvent {
+	monCfg := config.GetSystemConfig().Monitor
+
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	since := lastExpectCheck
+	lastExpectCheck = now
+
+	var events []expectEvent
+	for _, t := range targetsCfg.Targets {
+		if !t.Enabled {
+			continue
+		}
+		e := t.Expect
+		policy := monCfg.For(t)
+		snoozed := snoozedLocked(t.Mac, now)
+
+		if !since.IsZero() {
+			for _, o := range e.ClosedBetween(since, now) {
+				// A sighting since the window opened counts, even if it came just
+				// after the window closed but before this check.
+				if snoozed || !state[t.Mac].lastSeen.Before(o.Start) || presentLocked(t.Mac, policy, now) {
+					continue
+				}
+				message := e.LateMessage
+				if message == "" {
+					message = fmt.Sprintf("%s is not home yet (expected by %s).", t.Name, o.End.Format("15:04"))
+				}
+				events = append(events, expectEvent{target: t, message: message})
+			}
+		}
+
+		if e.AwayAlertHours > 0 {
+			// A device never seen since startup has no known departure time.
+			ds, ok := state[t.Mac]
+			if !ok || ds.awayAlerted || presentLocked(t.Mac, policy, now) {
+				continue
+			}
+			if now.Sub(ds.lastSeen) < time.Duration(e.AwayAlertHours)*time.Hour {
+				continue
+			}
+			ds.awayAlerted = true
+			state[t.Mac] = ds
+			if snoozed {
+				continue
+			}
+			message := e.AwayMessage
+			if message == "" {
+				message = fmt.Sprintf("%s has been away for %d hours.", t.Name, e.AwayAlertHours)
+			}
+			events = append(events, expectEvent{target: t, message: message})
+		}
+	}
+	return events
+}
+
+// onExpectEvent alerts the target's expectation receivers.
+func onExpectEvent(e expectEvent) {
+	log.Printf("Expectation alert for %q: %s", e.target.Name, e.message)
+	notify(e.target.Expect.ReceiversFor(e.target), e.target.QuietHours, func(config.Receiver) string { return e.message })
+}
@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+func resetExpectations() {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	lastExpectCheck = time.Time{}
+}
+
+func expectConfig(expect config.Expectation) config.TargetsConfig {
+	return config.TargetsConfig{Targets: []config.Target{
+		{Name: "Kid", Mac: phoneMac, Enabled: true, Expect: expect},
+	}}
+}
+
+func TestCheckExpectationsLateArrival(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetExpectations()
+
+	cfg := expectConfig(config.Expectation{
+		Timezone: "UTC",
+		Windows:  []config.TimeWindow{{Start: "15:00", End: "18:00"}},
+	})
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	if events := checkExpectations(cfg, day.Add(17*time.Hour)); len(events) != 0 {
+		t.Fatalf("the first check only sets the clock, got %+v", events)
+	}
+	if events := checkExpectations(cfg, day.Add(17*time.Hour+30*time.Minute)); len(events) != 0 {
+		t.Fatalf("the window is still open, got %+v", events)
+	}
+	events := checkExpectations(cfg, day.Add(18*time.Hour+time.Minute))
+	if len(events) != 1 || events[0].message != "Kid is not home yet (expected by 18:00)." {
+		t.Fatalf("expected one late alert, got %+v", events)
+	}
+	if events := checkExpectations(cfg, day.Add(18*time.Hour+2*time.Minute)); len(events) != 0 {
+		t.Errorf("a closed window should be reported once, got %+v", events)
+	}
+}
+
+func TestCheckExpectationsArrivedInTime(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetExpectations()
+
+	cfg := expectConfig(config.Expectation{
+		Timezone: "UTC",
+		Windows:  []config.TimeWindow{{Start: "15:00", End: "18:00"}},
+	})
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	checkExpectations(cfg, day.Add(17*time.Hour))
+	seen(phoneMac, day.Add(16*time.Hour))
+	if events := checkExpectations(cfg, day.Add(18*time.Hour+time.Minute)); len(events) != 0 {
+		t.Errorf("a device seen during the window should not alert, got %+v", events)
+	}
+}
+
+func TestCheckExpectationsAwayTooLong(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetExpectations()
+
+	cfg := expectConfig(config.Expectation{AwayAlertHours: 48})
+	now := time.Now()
+	seen(phoneMac, now.Add(-47*time.Hour))
+	missed(phoneMac, 3)
+
+	if events := checkExpectations(cfg, now); len(events) != 0 {
+		t.Fatalf("away for less than the limit, got %+v", events)
+	}
+	events := checkExpectations(cfg, now.Add(2*time.Hour))
+	if len(events) != 1 || events[0].message != "Kid has been away for 48 hours." {
+		t.Fatalf("expected one away alert, got %+v", events)
+	}
+	if events := checkExpectations(cfg, now.Add(3*time.Hour)); len(events) != 0 {
+		t.Errorf("the same absence should be reported once, got %+v", events)
+	}
+
+	// Coming back re-arms the alert.
+	updateStateAndShouldNotify(cfg.Targets[0])
+	stateMu.Lock()
+	rearmed := !state[phoneMac].awayAlerted
+	stateMu.Unlock()
+	if !rearmed {
+		t.Error("a sighting should re-arm the away alert")
+	}
+}
@@ -0,0 +1,57 @@
+package monitor
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/history"
+)
+
+// recordHistory adds the presence of every enabled target and person after a
+// scan cycle to the persisted visit history the statistics are built from.
+func recordHistory(targetsCfg config.TargetsConfig, now time.Time) {
+	monCfg := config.GetSystemConfig().Monitor
+	var obs []history.Observation
+
+	stateMu.Lock()
+	for _, t := range targetsCfg.Targets {
+		if !t.Enabled {
+			continue
+		}
+		at := state[t.Mac].lastSeen
+		if at.IsZero() {
+			at = now // home only through a forced presence
+		}
+		obs = append(obs, history.Observation{
+			Kind: history.KindTarget,
+			ID:   strings.ToLower(t.Mac),
+			Name: t.Name,
+			Home: presentLocked(t.Mac, monCfg.For(t), now),
+			At:   at,
+		})
+	}
+	for _, p := range targetsCfg.People {
+		ps, ok := people[p.Name]
+		if !p.Enabled || !ok {
+			continue
+		}
+		obs = append(obs, history.Observation{Kind: history.KindPerson, ID: p.Name, Name: p.Name, Home: ps.home, At: ps.lastHome})
+	}
+	stateMu.Unlock()
+
+	history.Record(obs, now)
+}
+
+// Stats returns the time-at-home statistics of every subject with history.
+// A non-empty name limits them to that target (name or MAC) or person.
+func Stats(name string, now time.Time) []history.Stats {
+	out := []history.Stats{}
+	for _, s := range history.Subjects() {
+		if name != "" && !strings.EqualFold(s.Name, name) && !strings.EqualFold(s.ID, name) {
+			continue
+		}
+		out = append(out, history.Compute(s, now, time.Local))
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+type householdState struct {
+	known     bool // false until the first evaluation sets a baseline
+	occupied  bool
+	since     time.Time
+	occupants []string
+}
+
+// household holds house-wide occupancy. Guarded by stateMu.
+var household householdState
+
+// householdEvent is a change in house-wide occupancy.
+type householdEvent struct {
+	occupied bool     // true: first arrival, false: house empty
+	who      []string // occupants after the change (the arrivals on first arrival)
+}
+
+// HouseholdStatus is a read-only snapshot of house-wide occupancy.
+type HouseholdStatus struct {
+	Occupied  bool      `json:"occupied"`
+	Since     time.Time `json:"since"`
+	Occupants []string  `json:"occupants"`
+}
+
+// HouseholdSnapshot returns the current house-wide occupancy.
+func HouseholdSnapshot() HouseholdStatus {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	return HouseholdStatus{
+		Occupied:  household.occupied,
+		Since:     household.since,
+		Occupants: append([]string(nil), household.occupants...),
+	}
+}
+
+// householdOccupants lists the household members currently home: enabled
+// people, plus enabled targets that do not belong to any person. Callers must
+// hold stateMu, and people must already be evaluated for this cycle.
+func householdOccupants(targetsCfg config.TargetsConfig, now time.Time) []string {
+	monCfg := config.GetSystemConfig().Monitor
+
+	owned := make(map[string]bool)
+	var occupants []string
+	for _, p := range targetsCfg.People {
+		for _, d := range p.Devices {
+			owned[strings.ToLower(d)] = true
+		}
+		if p.Enabled && people[p.Name].home {
+			occupants = append(occupants, p.Name)
+		}
+	}
+	for _, t := range targetsCfg.Targets {
+		if !t.Enabled || owned[strings.ToLower(t.Mac)] {
+			continue
+		}
+		if presentLocked(t.Mac, monCfg.For(t), now) {
+			occupants = append(occupants, t.Name)
+		}
+	}
+	return occupants
+}
+
+// evaluateHousehold updates house-wide occupancy and returns an event when the
+// house goes from empty to occupied or back. The first evaluation only sets a
+// baseline, so a restart does not announce a house that was already occupied.
+func evaluateHousehold(targetsCfg config.TargetsConfig, now time.Time) *householdEvent {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	occupants := householdOccupants(targetsCfg, now)
+	occupied := len(occupants) > 0
+	prev := household
+	household.occupants = occupants
+
+	if !prev.known {
+		household.known, household.occupied, household.since = true, occupied, now
+		return nil
+	}
+	if occupied == prev.occupied {
+		return nil
+	}
+	household.occupied, household.since = occupied, now
+	return &householdEvent{occupied: occupied, who: occupants}
+}
+
+// onHouseholdEvent notifies the household receivers of a first arrival or of
+// the house becoming empty.
+func onHouseholdEvent(e householdEvent, targetsCfg config.TargetsConfig) {
+	h := targetsCfg.Household
+	data := config.MessageData{Name: strings.Join(e.who, ", "), Event: config.TriggerHouseholdEmpty, Time: time.Now()}
+	if e.occupied {
+		log.Printf("Household occupied: first arrival %s.", data.Name)
+		data.Event = config.TriggerHouseholdOccupied
+	} else {
+		log.Println("Household empty: everyone has left.")
+	}
+
+	if !h.Enabled || h.MessageFor(config.Receiver{}, e.occupied) == "" {
+		return
+	}
+	notify(h.Receivers, h.QuietHours, func(r config.Receiver) string {
+		return renderFor(targetsCfg, r, h.MessageFor(r, e.occupied), data)
+	})
+}
@@ -0,0 +1,104 @@
+package monitor
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+func resetHousehold() {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	household = householdState{}
+}
+
+func TestEvaluateHouseholdFirstArrivalAndEmpty(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetPeople()
+	resetHousehold()
+
+	cfg := peopleConfig(config.PresenceAny)
+	cfg.Targets = append(cfg.Targets, config.Target{Name: "Laptop", Mac: "aa:bb:cc:dd:ee:03", Enabled: true})
+	now := time.Now()
+
+	// The first evaluation only sets the baseline (empty house).
+	evaluatePeople(cfg, now)
+	if e := evaluateHousehold(cfg, now); e != nil {
+		t.Fatalf("baseline evaluation should not emit, got %+v", e)
+	}
+
+	seen(phoneMac, now)
+	evaluatePeople(cfg, now)
+	e := evaluateHousehold(cfg, now)
+	if e == nil || !e.occupied {
+		t.Fatalf("expected a first-arrival event, got %+v", e)
+	}
+	if len(e.who) != 1 || e.who[0] != "Dad" {
+		t.Errorf("devices owned by a person should count as that person, got %v", e.who)
+	}
+
+	// A second member arriving is not a first arrival.
+	seen("aa:bb:cc:dd:ee:03", now)
+	evaluatePeople(cfg, now)
+	if e := evaluateHousehold(cfg, now); e != nil {
+		t.Errorf("second arrival should not emit, got %+v", e)
+	}
+
+	missed(phoneMac, 3)
+	missed("aa:bb:cc:dd:ee:03", 3)
+	later := now.Add(11 * time.Minute)
+	evaluatePeople(cfg, later)
+	e = evaluateHousehold(cfg, later)
+	if e == nil || e.occupied {
+		t.Fatalf("expected a house-empty event, got %+v", e)
+	}
+}
+
+func TestEvaluateHouseholdBaselineOccupied(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetPeople()
+	resetHousehold()
+
+	cfg := peopleConfig(config.PresenceAny)
+	now := time.Now()
+
+	seen(phoneMac, now)
+	evaluatePeople(cfg, now)
+	if e := evaluateHousehold(cfg, now); e != nil {
+		t.Errorf("an already-occupied house at startup should not be announced, got %+v", e)
+	}
+	if !HouseholdSnapshot().Occupied {
+		t.Error("household should be recorded as occupied")
+	}
+}
+
+func TestHouseholdMessagesAreRendered(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetPause()
+
+	cfg := config.TargetsConfig{Household: config.Household{
+		Enabled:        true,
+		ArrivalMessage: "{{.Name}} came home",
+		EmptyMessage:   "Nobody is home ({{.Event}})",
+		Receivers:      []config.Receiver{{ID: "household-1"}, {ID: "household-2", Message: "Welcome back, {{.Name}}"}},
+	}}
+	onHouseholdEvent(householdEvent{occupied: true, who: []string{"Mom", "Amy"}}, cfg)
+	onHouseholdEvent(householdEvent{}, cfg)
+
+	for id, want := range map[string][]string{
+		"household-1": {"Mom, Amy came home", "Nobody is home (household_empty)"},
+		"household-2": {"Welcome back, Mom, Amy", "Nobody is home (household_empty)"},
+	} {
+		var got []string
+		for _, e := range queued(id) {
+			got = append(got, e.Message.Text)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s got %q, want %q", id, got, want)
+		}
+	}
+}
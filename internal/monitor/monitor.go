@@ -2,13 +2,18 @@ package monitor
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/nekogravitycat/arp-notify/internal/arpscan"
 	"github.com/nekogravitycat/arp-notify/internal/config"
-	"github.com/nekogravitycat/arp-notify/internal/linebot"
+	"github.com/nekogravitycat/arp-notify/internal/inventory"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+	"github.com/nekogravitycat/arp-notify/internal/rules"
+	"github.com/nekogravitycat/arp-notify/internal/security"
 )
 
 // StartPeriodicScan runs arp-scan periodically. Config (targets and interval)
@@ -20,14 +25,11 @@ func StartPeriodicScan(ctx context.Context) {
 	ticker := time.NewTicker(time.Duration(interval) * time.Second)
 	defer ticker.Stop()
 
-	// Binary semaphore to allow only one scan at a time.
-	semaphore := make(chan struct{}, 1)
-
 	tryRun := func() {
 		select {
-		case semaphore <- struct{}{}:
-			defer func() { <-semaphore }()
-			runScanCycle()
+		case scanSemaphore <- struct{}{}:
+			defer func() { <-scanSemaphore }()
+			runScanCycle(config.GetTargetsConfig(), nil, nil)
 		default:
 			log.Println("Scan already in progress, skipping this interval.")
 		}
@@ -60,12 +62,127 @@ func StartPeriodicScan(ctx context.Context) {
 	}
 }
 
-// runScanCycle performs one detection pass honoring each target's detection mode.
-// At most one broadcast scan runs per cycle.
-func runScanCycle() {
-	arpCfg := config.GetSystemConfig().ArpScan
+// scanSemaphore is a binary semaphore allowing only one scan at a time, shared
+// by the periodic scan and on-demand scans.
+var scanSemaphore = make(chan struct{}, 1)
+
+// ErrScanInProgress is returned by RunScan when another scan is running.
+var ErrScanInProgress = errors.New("scan already in progress")
+
+// ScanResult is the outcome of looking for one target during a scan.
+type ScanResult struct {
+	Mac        string `json:"mac"`
+	Name       string `json:"name"`
+	Found      bool   `json:"found"`
+	Method     string `json:"method"` // ip | broadcast
+	IP         string `json:"ip,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RunScan runs a scan cycle immediately, unless one is already running, and
+// reports each target's result as soon as it is known. A non-empty filter
+// (target name or MAC) probes only that target; the whole-LAN consumers
+// (inventory, new devices, security) and the watchdog then skip this cycle.
+func RunScan(filter string, report func(ScanResult)) error {
 	targetsCfg := config.GetTargetsConfig()
 
+	var only []config.Target
+	if filter != "" {
+		for _, t := range targetsCfg.Targets {
+			if t.Enabled && (strings.EqualFold(t.Mac, filter) || t.Name == filter) {
+				only = append(only, t)
+			}
+		}
+		if len(only) == 0 {
+			return fmt.Errorf("no enabled target matches %q", filter)
+		}
+	}
+
+	select {
+	case scanSemaphore <- struct{}{}:
+		defer func() { <-scanSemaphore }()
+	default:
+		return ErrScanInProgress
+	}
+	log.Printf("On-demand scan requested (filter %q).", filter)
+	runScanCycle(targetsCfg, only, report)
+	return nil
+}
+
+// runScanCycle performs one detection pass and then re-evaluates the presence
+// of people and of the household from the updated device sightings. A non-nil
+// only limits the pass to those targets; such a partial pass says nothing about
+// the rest of the LAN, so it is not fed to the whole-LAN consumers or the
+// watchdog.
+func runScanCycle(targetsCfg config.TargetsConfig, only []config.Target, report func(ScanResult)) {
+	invCfg := config.GetSystemConfig().Inventory
+	wholeLAN := invCfg.Enabled || targetsCfg.NewDevices.Enabled || targetsCfg.Security.Enabled
+
+	partial := only != nil
+	scanCfg := targetsCfg
+	if partial {
+		scanCfg.Targets, wholeLAN = only, false
+	}
+	if report == nil {
+		report = func(ScanResult) {}
+	}
+	hosts, health := scanTargets(scanCfg, wholeLAN, report)
+
+	now := time.Now()
+	if invCfg.Enabled && !partial {
+		inventory.Record(hosts, now, invCfg.ReverseDNS)
+	}
+	// Presence changes are also collected as events for the rules.
+	events := targetTransitions(targetsCfg, hosts, now)
+	for _, e := range evaluatePeople(targetsCfg, now) {
+		onPersonEvent(e, targetsCfg)
+		trigger := config.TriggerDeparture
+		if e.arrived {
+			trigger = config.TriggerArrival
+		}
+		events = append(events, rules.Event{Trigger: trigger, Subject: e.person.Name, Time: now})
+	}
+	if e := evaluateHousehold(targetsCfg, now); e != nil {
+		onHouseholdEvent(*e, targetsCfg)
+		trigger := config.TriggerHouseholdEmpty
+		if e.occupied {
+			trigger = config.TriggerHouseholdOccupied
+		}
+		events = append(events, rules.Event{Trigger: trigger, Subject: strings.Join(e.who, ", "), Time: now})
+	}
+	if !partial {
+		for _, d := range detectNewDevices(hosts, targetsCfg, now) {
+			onNewDevice(d, targetsCfg.NewDevices)
+			events = append(events, rules.Event{Trigger: config.TriggerUnknownDevice, Mac: d.Mac, IP: d.IP, Time: now})
+		}
+	}
+	fireRules(targetsCfg, events)
+	recordHistory(targetsCfg, now)
+	publishPresence(targetsCfg, now)
+	if !partial {
+		for _, c := range security.Analyze(hosts, targetsCfg.Security, now) {
+			onConflict(c, targetsCfg.Security)
+		}
+	}
+	for _, e := range checkExpectations(targetsCfg, now) {
+		onExpectEvent(e)
+	}
+	if !partial {
+		recordCycle(health, targetsCfg.Admins, now)
+	}
+}
+
+// scanTargets looks for every enabled target honoring its detection mode and
+// returns the hosts seen by the broadcast scan, if one ran. At most one
+// broadcast scan runs per cycle; wholeLAN forces it for consumers that need
+// every host (new-device detection, the inventory, the security monitor).
+// Each target's outcome is passed to report; the returned health summarizes
+// how the arp-scan runs went, for the watchdog.
+func scanTargets(targetsCfg config.TargetsConfig, wholeLAN bool, report func(ScanResult)) ([]arpscan.Host, cycleHealth) {
+	var health cycleHealth
+	arpCfg := config.GetSystemConfig().ArpScan
+
 	// Collect enabled targets.
 	active := make([]config.Target, 0, len(targetsCfg.Targets))
 	for _, t := range targetsCfg.Targets {
@@ -73,8 +190,8 @@ func runScanCycle() {
 			active = append(active, t)
 		}
 	}
-	if len(active) == 0 {
-		return
+	if len(active) == 0 && !wholeLAN {
+		return nil, health
 	}
 
 	found := make(map[string]bool) // mac -> found this cycle
@@ -90,16 +207,31 @@ func runScanCycle() {
 
 		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(arpCfg.IndividualTimeoutSec)*time.Second)
 		log.Printf("Individual scan for %q (MAC %s, IP %s)", t.Name, t.Mac, t.Detection.IP)
+		start := time.Now()
 		output, err := arpscan.RunArpScanOnIp(ctx, arpCfg.Bin, arpCfg.Iface, t.Detection.IP)
 		cancel()
+		health.record(err)
+		result := ScanResult{
+			Mac: t.Mac, Name: t.Name, Method: config.ModeIP, IP: t.Detection.IP,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
 		if err != nil {
 			log.Printf("Error running individual arp-scan for IP %s: %v", t.Detection.IP, err)
+			if t.Detection.Mode == config.ModeIP {
+				result.Error = err.Error()
+				report(result)
+			}
 			continue
 		}
 
 		if containsMac(output, t.Mac) {
 			found[t.Mac] = true
-			onFound(t, targetsCfg.DefaultMessage)
+			onFound(t, t.Detection.IP, targetsCfg)
+			result.Found = true
+			report(result)
+		} else if t.Detection.Mode == config.ModeIP {
+			recordMiss(t.Mac)
+			report(result)
 		}
 	}
 
@@ -115,30 +247,53 @@ func runScanCycle() {
 			}
 		}
 	}
-	if len(needBroadcast) == 0 {
-		return
+	if len(needBroadcast) == 0 && !wholeLAN {
+		return nil, health
 	}
 
 	log.Println("Starting broadcast arp-scan...")
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(arpCfg.BroadcastTimeoutSec)*time.Second)
+	start := time.Now()
 	output, err := arpscan.RunArpScan(ctx, arpCfg.Bin, arpCfg.Iface)
 	cancel()
+	health.record(err)
+	elapsed := time.Since(start).Milliseconds()
 	if err != nil {
 		log.Printf("Error running broadcast arp-scan: %v", err)
-		return
+		for _, t := range needBroadcast {
+			report(ScanResult{Mac: t.Mac, Name: t.Name, Method: config.ModeBroadcast, DurationMs: elapsed, Error: err.Error()})
+		}
+		return nil, health
 	}
 
+	hosts := arpscan.ParseOutput(output)
+	health.emptyBroadcast = len(hosts) == 0
 	for _, t := range needBroadcast {
 		if found[t.Mac] {
 			continue
 		}
+		result := ScanResult{Mac: t.Mac, Name: t.Name, Method: config.ModeBroadcast, DurationMs: elapsed}
 		if containsMac(output, t.Mac) {
 			found[t.Mac] = true
-			onFound(t, targetsCfg.DefaultMessage)
+			result.Found, result.IP = true, hostIP(hosts, t.Mac)
+			onFound(t, result.IP, targetsCfg)
 		} else {
 			log.Printf("MAC %s (%q) not found.", t.Mac, t.Name)
+			recordMiss(t.Mac)
+		}
+		report(result)
+	}
+	return hosts, health
+}
+
+// hostIP returns the IP the MAC answered from in a broadcast scan.
+func hostIP(hosts []arpscan.Host, mac string) string {
+	for _, h := range hosts {
+		if strings.EqualFold(h.MAC, mac) {
+			return h.IP
 		}
 	}
+	return ""
 }
 
 // containsMac reports whether the scan output lists the MAC as a whole field
@@ -157,26 +312,29 @@ func containsMac(output, mac string) bool {
 }
 
 // onFound handles the event when a target MAC is found in a scan.
-func onFound(target config.Target, defaultMessage string) {
+func onFound(target config.Target, ip string, targetsCfg config.TargetsConfig) {
 	log.Printf("Target %q (MAC %s) found in scan output.", target.Name, target.Mac)
 
-	if !updateStateAndShouldNotify(target.Mac) {
+	now := time.Now()
+	data := config.MessageData{Name: target.Name, Event: config.TriggerArrival, Time: now, Mac: target.Mac, IP: ip}
+	if prev := lastSeen(target.Mac); !prev.IsZero() {
+		data.AwayFor = now.Sub(prev)
+	}
+	if !updateStateAndShouldNotify(target) {
 		log.Printf("Already notified for MAC %s, skipping notification.", target.Mac)
 		return
 	}
+	if p, ok := targetsCfg.PersonOf(target.Mac); ok {
+		// Announced once for the person instead of once per device.
+		log.Printf("MAC %s belongs to %q, leaving the notification to the person.", target.Mac, p.Name)
+		return
+	}
 
 	log.Printf("Sending notification for MAC %s.", target.Mac)
 
-	// Send each receiver its resolved message asynchronously.
-	for _, r := range target.Receivers {
-		go sendNotification(r.ID, target.MessageFor(r, defaultMessage))
-	}
-}
-
-func sendNotification(receiverID, message string) {
-	if err := linebot.SendMessage(receiverID, message); err != nil {
-		log.Printf("Error sending notification to %s: %v", receiverID, err)
-	} else {
-		log.Printf("Notification sent to %s", receiverID)
-	}
+	about := notifier.Message{Subject: target.Name, Mac: target.Mac, IP: ip, AwayFor: data.AwayFor, LineFlex: flexFor(target.LineFlex)}
+	quieted := notifyArrival(targetsCfg.Batching, about, target.Receivers, target.QuietHours, func(r config.Receiver) string {
+		return renderFor(targetsCfg, r, target.MessageFor(r, targetsCfg.DefaultMessage), data)
+	})
+	markQuieted(target.Mac, quieted)
 }
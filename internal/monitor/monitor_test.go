@@ -71,6 +71,9 @@ func resetState() {
 	stateMu.Lock()
 	defer stateMu.Unlock()
 	state = make(map[string]deviceState)
+	overrides = make(map[string]Override)
+	overridesLoaded = false
+	targetHome = make(map[string]bool)
 }
 
 func TestUpdateStateFirstSightingNotifies(t *testing.T) {
@@ -78,7 +81,7 @@ func TestUpdateStateFirstSightingNotifies(t *testing.T) {
 	resetState()
 
 	const mac = "aa:bb:cc:dd:ee:ff"
-	if !updateStateAndShouldNotify(mac) {
+	if !updateStateAndShouldNotify(config.Target{Mac: mac}) {
 		t.Error("first sighting should notify")
 	}
 
@@ -95,8 +98,8 @@ func TestUpdateStateSecondSightingSkips(t *testing.T) {
 	resetState()
 
 	const mac = "aa:bb:cc:dd:ee:ff"
-	updateStateAndShouldNotify(mac) // first: notifies
-	if updateStateAndShouldNotify(mac) {
+	updateStateAndShouldNotify(config.Target{Mac: mac}) // first: notifies
+	if updateStateAndShouldNotify(config.Target{Mac: mac}) {
 		t.Error("second sighting within the re-notify window should not notify")
 	}
 }
@@ -112,7 +115,42 @@ func TestUpdateStateReNotifiesAfterAbsence(t *testing.T) {
 	state[mac] = deviceState{lastSeen: time.Now().Add(-2 * time.Hour), notified: true}
 	stateMu.Unlock()
 
-	if !updateStateAndShouldNotify(mac) {
+	if !updateStateAndShouldNotify(config.Target{Mac: mac}) {
 		t.Error("device reappearing after the absence window should notify again")
 	}
 }
+
+func TestUpdateStateUsesTargetPolicy(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+
+	// The global window is a day, but this target re-notifies after 30 minutes.
+	tgt := config.Target{Mac: "aa:bb:cc:dd:ee:ff", Policy: config.PresencePolicy{AbsenceResetMin: 30}}
+
+	stateMu.Lock()
+	state[tgt.Mac] = deviceState{lastSeen: time.Now().Add(-time.Hour), notified: true}
+	stateMu.Unlock()
+
+	if !updateStateAndShouldNotify(tgt) {
+		t.Error("per-target absence_reset_min should override the global value")
+	}
+}
+
+func TestDevicePresentNeedsMissesAndDelay(t *testing.T) {
+	policy := config.MonitorConfig{DepartureDelayMin: 10, MissThreshold: 3}
+	now := time.Now()
+	old := now.Add(-time.Hour)
+
+	if !(deviceState{lastSeen: old, misses: 2}).present(policy, now) {
+		t.Error("device below the miss threshold should still be present")
+	}
+	if !(deviceState{lastSeen: now.Add(-5 * time.Minute), misses: 5}).present(policy, now) {
+		t.Error("device within the departure delay should still be present")
+	}
+	if (deviceState{lastSeen: old, misses: 3}).present(policy, now) {
+		t.Error("device past both thresholds should be away")
+	}
+	if (deviceState{}).present(policy, now) {
+		t.Error("never-seen device should not be present")
+	}
+}
@@ -0,0 +1,163 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/arpscan"
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+	"github.com/nekogravitycat/arp-notify/internal/statefile"
+)
+
+const knownDevicesPath = "known_devices.json"
+
+// NewDevice is a MAC seen on the LAN for the first time, kept until it is
+// added as a target or marked as known.
+type NewDevice struct {
+	Mac       string    `json:"mac"`
+	IP        string    `json:"ip"`
+	Vendor    string    `json:"vendor"`
+	FirstSeen time.Time `json:"firstSeen"`
+}
+
+type knownDevicesFile struct {
+	Known   map[string]time.Time `json:"known"` // lower-cased MAC -> first seen
+	Pending []NewDevice          `json:"pending"`
+}
+
+var (
+	knownMu     sync.Mutex
+	knownLoaded bool
+	known       knownDevicesFile
+)
+
+// loadKnownLocked reads the known-devices file on first use and reports
+// whether it existed. Callers must hold knownMu.
+func loadKnownLocked() (bool, error) {
+	if knownLoaded {
+		return true, nil
+	}
+	known = knownDevicesFile{Known: make(map[string]time.Time)}
+	existed, err := statefile.Load(knownDevicesPath, &known)
+	if err != nil {
+		return false, err
+	}
+	if known.Known == nil {
+		known.Known = make(map[string]time.Time)
+	}
+	knownLoaded = true
+	return existed, nil
+}
+
+// detectNewDevices records every host of a broadcast scan as known and returns
+// the ones never seen before that are neither targets nor allowlisted. The
+// first run without a known-devices file only learns the current LAN, so
+// enabling detection doesn't flood receivers with every existing device.
+func detectNewDevices(hosts []arpscan.Host, targetsCfg config.TargetsConfig, now time.Time) []NewDevice {
+	if !targetsCfg.NewDevices.Enabled || len(hosts) == 0 {
+		return nil
+	}
+
+	knownMu.Lock()
+	defer knownMu.Unlock()
+
+	existed, err := loadKnownLocked()
+	if err != nil {
+		log.Printf("Error loading known devices: %v", err)
+		return nil
+	}
+
+	targetMacs := configuredMacs(targetsCfg.Targets)
+	var fresh []NewDevice
+	changed := false
+	for _, h := range hosts {
+		if _, ok := known.Known[h.MAC]; ok {
+			continue
+		}
+		known.Known[h.MAC] = now
+		changed = true
+
+		if !existed {
+			continue // learning the baseline
+		}
+		if _, ok := targetMacs[h.MAC]; ok || targetsCfg.NewDevices.Allows(h.MAC) {
+			continue
+		}
+		d := NewDevice{Mac: h.MAC, IP: h.IP, Vendor: h.Vendor, FirstSeen: now}
+		known.Pending = append(known.Pending, d)
+		fresh = append(fresh, d)
+	}
+
+	if !existed {
+		log.Printf("New-device detection: learned %d device(s) already on the network.", len(known.Known))
+	}
+	if changed {
+		if err := statefile.Save(knownDevicesPath, known); err != nil {
+			log.Printf("Error saving known devices: %v", err)
+		}
+	}
+	return fresh
+}
+
+// onNewDevice alerts the new-device receivers about a never-seen MAC. With
+// server.public_url set the alert links to the device's row in the admin UI,
+// where it can be added as a target or marked as known.
+func onNewDevice(d NewDevice, cfg config.NewDevices) {
+	log.Printf("New device on the network: %s (%s, %s).", d.Mac, d.IP, d.Vendor)
+
+	header := cfg.Message
+	if header == "" {
+		header = "New device on the network"
+	}
+	vendor := d.Vendor
+	if vendor == "" {
+		vendor = "unknown vendor"
+	}
+	message := fmt.Sprintf("%s: %s (IP %s, %s)", header, d.Mac, d.IP, vendor)
+	if link := config.GetSystemConfig().Server.Link("status/new-device/" + d.Mac); link != "" {
+		message += "\nAdd it as a target or mark it as known: " + link
+	}
+	about := notifier.Message{Kind: config.TriggerUnknownDevice, Subject: vendor, Mac: d.Mac, IP: d.IP}
+	notifyAbout(about, cfg.Receivers, cfg.QuietHours, func(config.Receiver) string { return message })
+}
+
+// PendingNewDevices returns the new devices still awaiting a decision.
+func PendingNewDevices() []NewDevice {
+	knownMu.Lock()
+	defer knownMu.Unlock()
+	if _, err := loadKnownLocked(); err != nil {
+		log.Printf("Error loading known devices: %v", err)
+		return nil
+	}
+	return append([]NewDevice(nil), known.Pending...)
+}
+
+// DismissNewDevice removes a MAC from the pending new devices, once it has been
+// added as a target or marked as known.
+func DismissNewDevice(mac string) error {
+	knownMu.Lock()
+	defer knownMu.Unlock()
+	if _, err := loadKnownLocked(); err != nil {
+		return err
+	}
+
+	kept := known.Pending[:0]
+	found := false
+	for _, d := range known.Pending {
+		if strings.EqualFold(d.Mac, mac) {
+			found = true
+			continue
+		}
+		kept = append(kept, d)
+	}
+	known.Pending = kept
+	if !found {
+		return errors.New("no pending device with that mac")
+	}
+	return statefile.Save(knownDevicesPath, known)
+}
@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/arpscan"
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+func resetKnownDevices(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	knownMu.Lock()
+	defer knownMu.Unlock()
+	knownLoaded = false
+	known = knownDevicesFile{}
+}
+
+func newDevicesConfig() config.TargetsConfig {
+	return config.TargetsConfig{
+		Targets: []config.Target{{Name: "Phone", Mac: "AA:BB:CC:DD:EE:01", Enabled: true}},
+		NewDevices: config.NewDevices{
+			Enabled:   true,
+			Allowlist: []config.KnownDevice{{Mac: "aa:bb:cc:dd:ee:02", Name: "Printer"}},
+		},
+	}
+}
+
+func TestDetectNewDevicesLearnsBaselineFirst(t *testing.T) {
+	resetKnownDevices(t)
+	cfg := newDevicesConfig()
+	now := time.Now()
+
+	baseline := []arpscan.Host{{IP: "192.168.0.9", MAC: "aa:bb:cc:dd:ee:09"}}
+	if fresh := detectNewDevices(baseline, cfg, now); len(fresh) != 0 {
+		t.Fatalf("the first scan should only learn the LAN, got %+v", fresh)
+	}
+	if fresh := detectNewDevices(baseline, cfg, now); len(fresh) != 0 {
+		t.Errorf("a learned device should not alert, got %+v", fresh)
+	}
+}
+
+func TestDetectNewDevicesAlertsOnce(t *testing.T) {
+	resetKnownDevices(t)
+	cfg := newDevicesConfig()
+	now := time.Now()
+	detectNewDevices([]arpscan.Host{{IP: "192.168.0.9", MAC: "aa:bb:cc:dd:ee:09"}}, cfg, now)
+
+	hosts := []arpscan.Host{
+		{IP: "192.168.0.1", MAC: "aa:bb:cc:dd:ee:01"},                     // target
+		{IP: "192.168.0.2", MAC: "aa:bb:cc:dd:ee:02"},                     // allowlisted
+		{IP: "192.168.0.3", MAC: "aa:bb:cc:dd:ee:03", Vendor: "Acme Inc"}, // new
+	}
+	fresh := detectNewDevices(hosts, cfg, now)
+	if len(fresh) != 1 || fresh[0].Mac != "aa:bb:cc:dd:ee:03" || fresh[0].Vendor != "Acme Inc" {
+		t.Fatalf("expected only the unknown device, got %+v", fresh)
+	}
+	if fresh := detectNewDevices(hosts, cfg, now); len(fresh) != 0 {
+		t.Errorf("a device should alert only once, got %+v", fresh)
+	}
+
+	// The known set survives a restart.
+	knownMu.Lock()
+	knownLoaded = false
+	knownMu.Unlock()
+	if pending := PendingNewDevices(); len(pending) != 1 {
+		t.Fatalf("expected 1 pending device after reload, got %+v", pending)
+	}
+
+	if err := DismissNewDevice("AA:BB:CC:DD:EE:03"); err != nil {
+		t.Fatalf("DismissNewDevice: %v", err)
+	}
+	if pending := PendingNewDevices(); len(pending) != 0 {
+		t.Errorf("dismissed device still pending: %+v", pending)
+	}
+	if err := DismissNewDevice("aa:bb:cc:dd:ee:03"); err == nil {
+		t.Error("dismissing an unknown device should fail")
+	}
+}
+
+func TestDetectNewDevicesDisabled(t *testing.T) {
+	resetKnownDevices(t)
+	cfg := newDevicesConfig()
+	cfg.NewDevices.Enabled = false
+
+	if fresh := detectNewDevices([]arpscan.Host{{IP: "192.168.0.3", MAC: "aa:bb:cc:dd:ee:03"}}, cfg, time.Now()); fresh != nil {
+		t.Errorf("disabled detection should not report devices, got %+v", fresh)
+	}
+}
+
+func TestNewDeviceAlertLinksToAdminUI(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetPause()
+	sys := config.GetSystemConfig()
+	sys.Server.PublicURL = "https://home.example.com/"
+	if err := config.SaveSystemConfig(sys); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDevice{Mac: "aa:bb:cc:dd:ee:09", IP: "192.168.0.9"}
+	onNewDevice(d, config.NewDevices{Enabled: true, Receivers: []config.Receiver{{ID: "new-device-1"}}})
+	q := queued("new-device-1")
+	if len(q) != 1 || !strings.HasSuffix(q[0].Message.Text, "https://home.example.com/#status/new-device/aa:bb:cc:dd:ee:09") {
+		t.Errorf("alert = %+v, want a link to the device", q)
+	}
+}
@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"log"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+	"github.com/nekogravitycat/arp-notify/internal/outbox"
+)
+
+// notify queues each receiver's resolved message in the outbox, holding back
+// those that fall inside the sender's quiet hours or, failing that, the
+// receiver's own. Deferred ones wait in the outbox until the window ends. It
+// reports whether any notification was held back. Nothing is sent while
+// notifications are paused.
+func notify(receivers []config.Receiver, quiet config.Schedule, messageFor func(config.Receiver) string) bool {
+	return notifyAbout(notifier.Message{}, receivers, quiet, messageFor)
+}
+
+// notifyAbout is notify for a message about an event; about carries its
+// details (kind, subject, IP) for channels that can show them.
+func notifyAbout(about notifier.Message, receivers []config.Receiver, quiet config.Schedule, messageFor func(config.Receiver) string) bool {
+	if len(receivers) > 0 && Paused() {
+		log.Printf("Paused: dropped notification to %d receiver(s).", len(receivers))
+		return false
+	}
+	return deliverVia(about, receivers, quiet, messageFor, outbox.Enqueue)
+}
+
+// deliver is notify without the pause check, for operational alerts that must
+// reach admins even while notifications are paused.
+func deliver(receivers []config.Receiver, quiet config.Schedule, messageFor func(config.Receiver) string) bool {
+	return deliverVia(notifier.Message{Kind: "alert"}, receivers, quiet, messageFor, outbox.Enqueue)
+}
+
+// renderFor renders a message template for one receiver.
+func renderFor(targetsCfg config.TargetsConfig, r config.Receiver, text string, data config.MessageData) string {
+	data.Receiver = targetsCfg.ContactName(r.ID)
+	return config.RenderMessage(text, data)
+}
+
+// flexFor returns the LINE Flex layout to send with a subject's messages, nil
+// when it is not enabled.
+func flexFor(f config.LineFlex) *config.LineFlex {
+	if !f.Enabled {
+		return nil
+	}
+	return &f
+}
+
+// deliverVia applies quiet hours like deliver, handing the messages that may
+// go out now to send.
+func deliverVia(about notifier.Message, receivers []config.Receiver, quiet config.Schedule, messageFor func(config.Receiver) string, send func(receiverID string, msg notifier.Message)) bool {
+	now := time.Now()
+	if about.Time.IsZero() {
+		about.Time = now
+	}
+	quieted := false
+	for _, r := range receivers {
+		message := about
+		message.Text = messageFor(r)
+
+		schedule := quiet
+		active, until := schedule.ActiveAt(now)
+		if !active {
+			schedule = r.QuietHours
+			active, until = schedule.ActiveAt(now)
+		}
+		if !active {
+			send(r.ID, message)
+			continue
+		}
+
+		quieted = true
+		if schedule.ActionOrDefault() == config.QuietDefer {
+			log.Printf("Quiet hours: deferring notification to %s until %s.", r.ID, until.Format(time.DateTime))
+			outbox.Defer(r.ID, message, until)
+		} else {
+			log.Printf("Quiet hours: suppressed notification to %s.", r.ID)
+		}
+	}
+	return quieted
+}
@@ -0,0 +1,55 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/outbox"
+)
+
+// queued returns the outbox entries for one receiver. The outbox is shared
+// by the tests, so each test uses its own receiver IDs.
+func queued(receiverID string) []outbox.Entry {
+	var out []outbox.Entry
+	for _, e := range outbox.Entries() {
+		if e.ReceiverID == receiverID {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// alwaysQuiet is a schedule whose single window covers the whole day.
+func alwaysQuiet(action string) config.Schedule {
+	return config.Schedule{Action: action, Windows: []config.TimeWindow{{Start: "00:00", End: "00:00"}}}
+}
+
+func TestNotifySuppressedByTargetQuietHours(t *testing.T) {
+	t.Chdir(t.TempDir())
+	resetPause()
+
+	receivers := []config.Receiver{{ID: "suppressed-1"}, {ID: "suppressed-2"}}
+	if !notify(receivers, alwaysQuiet(config.QuietSuppress), func(config.Receiver) string { return "hi" }) {
+		t.Error("notify should report that notifications were held back")
+	}
+	if n := len(queued("suppressed-1")) + len(queued("suppressed-2")); n != 0 {
+		t.Errorf("suppressed notifications should not be queued, got %d", n)
+	}
+}
+
+func TestNotifyDeferredByReceiverQuietHours(t *testing.T) {
+	t.Chdir(t.TempDir())
+	resetPause()
+
+	receivers := []config.Receiver{{ID: "deferred-1", QuietHours: alwaysQuiet(config.QuietDefer)}}
+	if !notify(receivers, config.Schedule{}, func(r config.Receiver) string { return "hello " + r.ID }) {
+		t.Error("notify should report that notifications were held back")
+	}
+
+	// The notification waits in the outbox until the window ends.
+	q := queued("deferred-1")
+	if len(q) != 1 || q[0].Message.Text != "hello deferred-1" || !q[0].Deferred || !q[0].NextAttempt.After(time.Now()) {
+		t.Fatalf("expected one deferred notification, got %+v", q)
+	}
+}
@@ -0,0 +1,174 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/statefile"
+)
+
+const overridesPath = "overrides.json"
+
+// Forced presence values of an override.
+const (
+	ForcePresent = "present"
+	ForceAbsent  = "absent"
+)
+
+// Override is a manual adjustment of one target: a forced presence, a snooze
+// of its notifications, or both. A zero Until keeps the forced presence until
+// it is cleared.
+type Override struct {
+	Mac         string    `json:"mac"`
+	Presence    string    `json:"presence,omitempty"` // present | absent | "" (none)
+	Until       time.Time `json:"until,omitzero"`
+	SnoozeUntil time.Time `json:"snoozeUntil,omitzero"`
+}
+
+// forcing reports whether the override forces a presence at now.
+func (o Override) forcing(now time.Time) bool {
+	return o.Presence != "" && (o.Until.IsZero() || now.Before(o.Until))
+}
+
+// snoozed reports whether the override silences notifications at now.
+func (o Override) snoozed(now time.Time) bool {
+	return now.Before(o.SnoozeUntil)
+}
+
+// overrides holds the active overrides keyed by lower-cased MAC. Guarded by
+// stateMu, like the device state they adjust.
+var (
+	overrides       = make(map[string]Override)
+	overridesLoaded bool
+)
+
+// loadOverridesLocked reads the overrides file on first use. Callers must hold
+// stateMu.
+func loadOverridesLocked() {
+	if overridesLoaded {
+		return
+	}
+	overridesLoaded = true
+	var list []Override
+	if _, err := statefile.Load(overridesPath, &list); err != nil {
+		log.Printf("Error loading overrides, starting without any: %v", err)
+	}
+	for _, o := range list {
+		overrides[strings.ToLower(o.Mac)] = o
+	}
+}
+
+// overrideLocked returns the MAC's override if any part of it is still in
+// effect, dropping it once it has fully expired. Callers must hold stateMu.
+func overrideLocked(mac string, now time.Time) (Override, bool) {
+	loadOverridesLocked()
+	key := strings.ToLower(mac)
+	o, ok := overrides[key]
+	if !ok {
+		return Override{}, false
+	}
+	if !o.forcing(now) && !o.snoozed(now) {
+		delete(overrides, key)
+		saveOverridesLocked()
+		return Override{}, false
+	}
+	return o, true
+}
+
+func saveOverridesLocked() {
+	list := make([]Override, 0, len(overrides))
+	for _, o := range overrides {
+		list = append(list, o)
+	}
+	if err := statefile.Save(overridesPath, list); err != nil {
+		log.Printf("Error saving overrides: %v", err)
+	}
+}
+
+// snoozedLocked reports whether the MAC's notifications are snoozed at now.
+// Callers must hold stateMu.
+func snoozedLocked(mac string, now time.Time) bool {
+	o, ok := overrideLocked(mac, now)
+	return ok && o.snoozed(now)
+}
+
+// presentLocked reports whether the device counts as home, honoring a forced
+// presence before its sightings. Callers must hold stateMu.
+func presentLocked(mac string, policy config.MonitorConfig, now time.Time) bool {
+	if o, ok := overrideLocked(mac, now); ok && o.forcing(now) {
+		return o.Presence == ForcePresent
+	}
+	return state[mac].present(policy, now)
+}
+
+// updateOverride applies change to the MAC's override and persists the result.
+func updateOverride(mac string, change func(*Override)) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	loadOverridesLocked()
+	key := strings.ToLower(mac)
+	o := overrides[key]
+	o.Mac = mac
+	change(&o)
+	if o.Presence == "" && o.SnoozeUntil.IsZero() {
+		delete(overrides, key)
+	} else {
+		overrides[key] = o
+	}
+	saveOverridesLocked()
+}
+
+// ForcePresence pins the target's presence to present or absent until the
+// given time (zero = until cleared). An empty presence removes the pin.
+func ForcePresence(mac, presence string, until time.Time) error {
+	switch presence {
+	case "", ForcePresent, ForceAbsent:
+	default:
+		return fmt.Errorf("invalid presence %q (expected present|absent)", presence)
+	}
+	if presence != "" && !until.IsZero() && !until.After(time.Now()) {
+		return fmt.Errorf("until must be in the future")
+	}
+	updateOverride(mac, func(o *Override) {
+		o.Presence, o.Until = presence, until
+		if presence == "" {
+			o.Until = time.Time{}
+		}
+	})
+	return nil
+}
+
+// Snooze silences the target's notifications until the given time. A zero
+// time ends the snooze.
+func Snooze(mac string, until time.Time) error {
+	if !until.IsZero() && !until.After(time.Now()) {
+		return fmt.Errorf("until must be in the future")
+	}
+	updateOverride(mac, func(o *Override) { o.SnoozeUntil = until })
+	return nil
+}
+
+// ClearOverride removes every override of the target.
+func ClearOverride(mac string) {
+	updateOverride(mac, func(o *Override) { *o = Override{} })
+}
+
+// Overrides returns the overrides still in effect.
+func Overrides() []Override {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	loadOverridesLocked()
+	now := time.Now()
+	out := make([]Override, 0, len(overrides))
+	for _, o := range overrides {
+		if active, ok := overrideLocked(o.Mac, now); ok {
+			out = append(out, active)
+		}
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+func TestSnoozeSilencesArrival(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+
+	const mac = "aa:bb:cc:dd:ee:ff"
+	if err := Snooze(mac, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if updateStateAndShouldNotify(config.Target{Mac: mac}) {
+		t.Error("a snoozed target should not notify")
+	}
+
+	// The arrival was recorded, so ending the snooze doesn't announce it late.
+	if err := Snooze(mac, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if updateStateAndShouldNotify(config.Target{Mac: mac}) {
+		t.Error("an arrival silenced by a snooze should not be announced afterwards")
+	}
+}
+
+func TestSnoozeSilencesDeparture(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+
+	const mac = "aa:bb:cc:dd:ee:01"
+	cfg := config.TargetsConfig{Targets: []config.Target{{Name: "Phone", Mac: mac, Enabled: true}}}
+	now := time.Now()
+	seen(mac, now)
+	targetTransitions(cfg, nil, now) // baseline: home
+
+	if err := Snooze(mac, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	seen(mac, now.Add(-2*time.Hour))
+	missed(mac, 10)
+	if events := targetTransitions(cfg, nil, now); len(events) != 0 {
+		t.Errorf("a snoozed target's departure produced rule events: %+v", events)
+	}
+}
+
+func TestSnoozeSilencesPerson(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetPeople()
+
+	cfg := peopleConfig(config.PresenceAny)
+	now := time.Now()
+	evaluatePeople(cfg, now) // start tracking while away
+
+	if err := Snooze(phoneMac, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	seen(phoneMac, now)
+	if events := evaluatePeople(cfg, now); len(events) != 0 {
+		t.Errorf("arrival of a person with a snoozed device produced events: %+v", events)
+	}
+
+	later := now.Add(11 * time.Minute)
+	missed(phoneMac, 3)
+	if events := evaluatePeople(cfg, later); len(events) != 0 {
+		t.Errorf("departure of a person with a snoozed device produced events: %+v", events)
+	}
+}
+
+func TestForceAbsentHoldsArrival(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+
+	const mac = "aa:bb:cc:dd:ee:ff"
+	policy := config.GetSystemConfig().Monitor
+	if err := ForcePresence(mac, ForceAbsent, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if updateStateAndShouldNotify(config.Target{Mac: mac}) {
+		t.Error("a target forced away should not notify")
+	}
+
+	stateMu.Lock()
+	present := presentLocked(mac, policy, time.Now())
+	stateMu.Unlock()
+	if present {
+		t.Error("a target forced away should read as away even when seen")
+	}
+
+	ClearOverride(mac)
+	if !updateStateAndShouldNotify(config.Target{Mac: mac}) {
+		t.Error("the held-back arrival should notify once the override is cleared")
+	}
+}
+
+func TestForcePresentExpires(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+
+	const mac = "aa:bb:cc:dd:ee:ff"
+	policy := config.GetSystemConfig().Monitor
+	if err := ForcePresence(mac, ForcePresent, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if !presentLocked(mac, policy, time.Now()) {
+		t.Error("a never-seen target forced present should read as home")
+	}
+	if presentLocked(mac, policy, time.Now().Add(2*time.Hour)) {
+		t.Error("the forced presence should end at its expiry")
+	}
+	if _, ok := overrides[mac]; ok {
+		t.Error("an expired override should be dropped")
+	}
+}
+
+func TestOverridesPersist(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+
+	const mac = "AA:BB:CC:DD:EE:FF"
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := Snooze(mac, until); err != nil {
+		t.Fatal(err)
+	}
+
+	resetState() // forget the in-memory copy, as after a restart
+	got := Overrides()
+	if len(got) != 1 || got[0].Mac != mac || !got[0].SnoozeUntil.Equal(until) {
+		t.Fatalf("overrides after reload = %+v", got)
+	}
+}
+
+func TestOverrideRejectsBadInput(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+
+	if err := ForcePresence("aa:bb:cc:dd:ee:ff", "maybe", time.Time{}); err == nil {
+		t.Error("expected error for an unknown presence")
+	}
+	if err := Snooze("aa:bb:cc:dd:ee:ff", time.Now().Add(-time.Hour)); err == nil {
+		t.Error("expected error for a snooze in the past")
+	}
+}
@@ -0,0 +1,103 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/statefile"
+)
+
+const pausePath = "pause.json"
+
+// PauseStatus describes the global pause (vacation mode). While paused, scans
+// keep running so presence stays accurate, but no notification is sent.
+type PauseStatus struct {
+	Paused bool      `json:"paused"`
+	Since  time.Time `json:"since,omitzero"`
+	Until  time.Time `json:"until,omitzero"` // zero = until resumed
+	Reason string    `json:"reason,omitempty"`
+}
+
+// String describes the pause for logs and chat replies.
+func (p PauseStatus) String() string {
+	if !p.Paused {
+		return "not paused"
+	}
+	s := "paused until resumed"
+	if !p.Until.IsZero() {
+		s = "paused until " + p.Until.Local().Format("2006-01-02 15:04")
+	}
+	if p.Reason != "" {
+		s += " (" + p.Reason + ")"
+	}
+	return s
+}
+
+var (
+	pauseMu     sync.Mutex
+	pauseLoaded bool
+	pause       PauseStatus
+)
+
+// pauseLocked returns the current pause, ending it once its end time has
+// passed. Callers must hold pauseMu.
+func pauseLocked(now time.Time) PauseStatus {
+	if !pauseLoaded {
+		pauseLoaded = true
+		if _, err := statefile.Load(pausePath, &pause); err != nil {
+			log.Printf("Error loading pause state, starting unpaused: %v", err)
+		}
+	}
+	if pause.Paused && !pause.Until.IsZero() && !now.Before(pause.Until) {
+		log.Println("Pause ended, notifications resumed.")
+		setPauseLocked(PauseStatus{})
+	}
+	return pause
+}
+
+func setPauseLocked(p PauseStatus) {
+	pause = p
+	if err := statefile.Save(pausePath, pause); err != nil {
+		log.Printf("Error saving pause state: %v", err)
+	}
+}
+
+// Paused reports whether notifications are currently paused.
+func Paused() bool {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	return pauseLocked(time.Now()).Paused
+}
+
+// CurrentPause returns the pause status for the status view.
+func CurrentPause() PauseStatus {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	return pauseLocked(time.Now())
+}
+
+// Pause stops all notifications until the given time (zero = until resumed).
+func Pause(until time.Time, reason string) error {
+	now := time.Now()
+	if !until.IsZero() && !until.After(now) {
+		return fmt.Errorf("until must be in the future")
+	}
+
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	pauseLocked(now)
+	setPauseLocked(PauseStatus{Paused: true, Since: now, Until: until, Reason: reason})
+	log.Printf("Notifications %s.", pause)
+	return nil
+}
+
+// Resume ends the pause immediately.
+func Resume() {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	pauseLocked(time.Now())
+	setPauseLocked(PauseStatus{})
+	log.Println("Notifications resumed.")
+}
@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/security"
+)
+
+func resetPause() {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	pauseLoaded = false
+	pause = PauseStatus{}
+}
+
+func TestPauseDropsNotifications(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetPause()
+
+	if err := Pause(time.Time{}, "trip"); err != nil {
+		t.Fatal(err)
+	}
+	receivers := []config.Receiver{{ID: "paused-1", QuietHours: alwaysQuiet(config.QuietDefer)}}
+	notify(receivers, config.Schedule{}, func(config.Receiver) string { return "hi" })
+
+	if n := len(queued("paused-1")); n != 0 {
+		t.Errorf("nothing should be queued while paused, got %d", n)
+	}
+
+	Resume()
+	if Paused() {
+		t.Error("Resume should end the pause")
+	}
+}
+
+func TestSecurityAlertsIgnorePause(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetPause()
+
+	if err := Pause(time.Time{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer Resume()
+	onConflict(security.Conflict{Kind: security.KindDuplicateIP, IP: "192.168.0.1", Macs: []string{"aa:bb:cc:dd:ee:01", "aa:bb:cc:dd:ee:02"}},
+		config.Security{Receivers: []config.Receiver{{ID: "security-1"}}})
+	if n := len(queued("security-1")); n != 1 {
+		t.Errorf("security alert while paused: %d queued, want 1", n)
+	}
+}
+
+func TestPauseExpiresAndPersists(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetPause()
+
+	if err := Pause(time.Now().Add(-time.Minute), ""); err == nil {
+		t.Error("expected error for an end time in the past")
+	}
+	if err := Pause(time.Now().Add(time.Hour), "trip"); err != nil {
+		t.Fatal(err)
+	}
+
+	resetPause() // reload from disk, as after a restart
+	if p := CurrentPause(); !p.Paused || p.Reason != "trip" {
+		t.Fatalf("pause after reload = %+v", p)
+	}
+
+	pauseMu.Lock()
+	expired := pauseLocked(time.Now().Add(2 * time.Hour))
+	pauseMu.Unlock()
+	if expired.Paused {
+		t.Error("the pause should end at its end time")
+	}
+}
+
+func TestHandleChatCommand(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetPause()
+
+	cfg := config.GetTargetsConfig()
+	cfg.Household.Receivers = []config.Receiver{{ID: "U1"}}
+	if err := config.SaveTargetsConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, handled := HandleChatCommand("U1", "hello"); handled {
+		t.Error("plain text should not be handled")
+	}
+	if _, handled := HandleChatCommand("U2", "pause"); !handled || Paused() {
+		t.Error("a stranger should be answered but not allowed to pause")
+	}
+
+	if _, handled := HandleChatCommand("U1", "Pause 3d family trip"); !handled {
+		t.Fatal("pause should be handled")
+	}
+	p := CurrentPause()
+	if !p.Paused || p.Reason != "family trip" || time.Until(p.Until) < 71*time.Hour {
+		t.Errorf("pause = %+v, want 3 days with reason", p)
+	}
+
+	HandleChatCommand("U1", "resume")
+	if Paused() {
+		t.Error("resume should end the pause")
+	}
+}
+
+func TestIsReceiverCoversEveryReceiverList(t *testing.T) {
+	cfg := config.TargetsConfig{
+		Targets: []config.Target{{Name: "Phone", Expect: config.Expectation{Receivers: []config.Receiver{{ID: "U1"}}}}},
+		Admins:  []config.Receiver{{ID: "telegram:2"}},
+		Rules: []config.Rule{{Actions: []config.RuleAction{
+			{Type: config.ActionNotify, Receivers: []config.Receiver{{ID: "U3"}}},
+		}}},
+	}
+	for _, id := range []string{"U1", "line:U1", "telegram:2", "U3"} {
+		if !isReceiver(cfg, id) {
+			t.Errorf("isReceiver(%q) = false, want true", id)
+		}
+	}
+	if isReceiver(cfg, "U4") {
+		t.Error("a stranger should not be a receiver")
+	}
+}
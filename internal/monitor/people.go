@@ -0,0 +1,188 @@
+package monitor
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+type personState struct {
+	home      bool
+	since     time.Time // when home last flipped
+	lastHome  time.Time // last evaluation that found the person home
+	notified  bool
+	announced bool // the arrival of the current stay was announced
+}
+
+// people holds per-person presence, keyed by person name. Guarded by stateMu.
+var people = make(map[string]personState)
+
+// personEvent is a presence transition of a person.
+type personEvent struct {
+	person  config.Person
+	arrived bool          // false means departed
+	awayFor time.Duration // for an arrival, how long the person was away; 0 if unknown
+}
+
+// PersonStatus is a read-only snapshot of a tracked person, exposed to the web UI.
+type PersonStatus struct {
+	Name     string    `json:"name"`
+	Home     bool      `json:"home"`
+	Since    time.Time `json:"since"`
+	LastSeen time.Time `json:"lastSeen"`
+	Notified bool      `json:"notified"`
+}
+
+// PeopleSnapshot returns the current person states for the status view.
+func PeopleSnapshot() []PersonStatus {
+	targetsCfg := config.GetTargetsConfig()
+
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	macs := configuredMacs(targetsCfg.Targets)
+	out := make([]PersonStatus, 0, len(people))
+	for _, p := range targetsCfg.People {
+		ps, ok := people[p.Name]
+		if !ok {
+			continue
+		}
+		s := PersonStatus{Name: p.Name, Home: ps.home, Since: ps.since, Notified: ps.notified}
+		for _, d := range p.Devices {
+			if ds, ok := state[macs[strings.ToLower(d)]]; ok && ds.lastSeen.After(s.LastSeen) {
+				s.LastSeen = ds.lastSeen
+			}
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// configuredMacs maps lower-cased MACs to the spelling used in the targets
+// config, which is the key device state is stored under.
+func configuredMacs(targets []config.Target) map[string]string {
+	out := make(map[string]string, len(targets))
+	for _, t := range targets {
+		out[strings.ToLower(t.Mac)] = t.Mac
+	}
+	return out
+}
+
+// evaluatePeople recomputes every enabled person's presence from the latest
+// sightings of their enabled devices and returns the resulting arrivals and
+// departures. Arrivals follow the same re-notify window as single devices: a
+// person who comes back within absence_reset_min is not announced again. A
+// person with a snoozed device produces no events.
+func evaluatePeople(targetsCfg config.TargetsConfig, now time.Time) []personEvent {
+	monCfg := config.GetSystemConfig().Monitor
+	resetAfter := time.Duration(monCfg.AbsenceResetMin) * time.Minute
+
+	enabled := make(map[string]config.Target) // lower-cased MAC -> target
+	for _, t := range targetsCfg.Targets {
+		if t.Enabled {
+			enabled[strings.ToLower(t.Mac)] = t
+		}
+	}
+
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	var events []personEvent
+	configured := make(map[string]bool, len(targetsCfg.People))
+	for _, p := range targetsCfg.People {
+		configured[p.Name] = true
+		if !p.Enabled {
+			continue
+		}
+
+		present, total, snoozed := 0, 0, false
+		for _, d := range p.Devices {
+			t, ok := enabled[strings.ToLower(d)]
+			if !ok {
+				continue
+			}
+			total++
+			snoozed = snoozed || snoozedLocked(t.Mac, now)
+			if presentLocked(t.Mac, monCfg.For(t), now) {
+				present++
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		home := present > 0
+		if p.RequiresAll() {
+			home = present == total
+		}
+
+		ps, exists := people[p.Name]
+		switch {
+		case home && !ps.home:
+			if !exists || now.Sub(ps.lastHome) > resetAfter {
+				ps.notified = false
+			}
+			ps.home, ps.since, ps.announced = true, now, false
+			if !ps.notified {
+				ps.notified = true
+				if snoozed {
+					break // recorded as announced, like a snoozed device
+				}
+				ps.announced = true
+				var away time.Duration
+				if !ps.lastHome.IsZero() {
+					away = now.Sub(ps.lastHome)
+				}
+				events = append(events, personEvent{person: p, arrived: true, awayFor: away})
+			}
+		case !home && ps.home:
+			// A departure is only announced after an announced arrival.
+			if ps.announced && !snoozed {
+				events = append(events, personEvent{person: p, arrived: false})
+			}
+			ps.home, ps.since, ps.announced = false, now, false
+		case !exists:
+			// First evaluation while away: start tracking without an event.
+			ps.since = now
+		}
+		if home {
+			ps.lastHome = now
+		}
+		people[p.Name] = ps
+	}
+
+	// Forget people that were removed or renamed in the config.
+	for name := range people {
+		if !configured[name] {
+			delete(people, name)
+		}
+	}
+	return events
+}
+
+// onPersonEvent notifies a person's receivers of an arrival, or of a departure
+// when the person has a departure message.
+func onPersonEvent(e personEvent, targetsCfg config.TargetsConfig) {
+	p := e.person
+	data := config.MessageData{Name: p.Name, Event: config.TriggerArrival, Time: time.Now(), AwayFor: e.awayFor}
+	if e.arrived {
+		log.Printf("Person %q arrived, sending notification.", p.Name)
+		about := notifier.Message{Subject: p.Name, AwayFor: e.awayFor, LineFlex: flexFor(p.LineFlex)}
+		notifyArrival(targetsCfg.Batching, about, p.Receivers, p.QuietHoursFor(targetsCfg.Targets), func(r config.Receiver) string {
+			return renderFor(targetsCfg, r, p.MessageFor(r, targetsCfg.DefaultMessage), data)
+		})
+		return
+	}
+
+	log.Printf("Person %q departed.", p.Name)
+	if p.DepartureMessage == "" {
+		return
+	}
+	data.Event = config.TriggerDeparture
+	about := notifier.Message{Kind: config.TriggerDeparture, Subject: p.Name, LineFlex: flexFor(p.LineFlex)}
+	notifyAbout(about, p.Receivers, p.QuietHoursFor(targetsCfg.Targets), func(r config.Receiver) string {
+		return renderFor(targetsCfg, r, p.DepartureMessage, data)
+	})
+}
@@ -0,0 +1,186 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+const (
+	phoneMac = "aa:bb:cc:dd:ee:01"
+	watchMac = "aa:bb:cc:dd:ee:02"
+)
+
+func resetPeople() {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	people = make(map[string]personState)
+}
+
+func peopleConfig(presence string) config.TargetsConfig {
+	return config.TargetsConfig{
+		Targets: []config.Target{
+			{Name: "Phone", Mac: phoneMac, Enabled: true},
+			{Name: "Watch", Mac: watchMac, Enabled: true},
+		},
+		People: []config.Person{
+			{Name: "Dad", Enabled: true, Devices: []string{phoneMac, watchMac}, Presence: presence},
+		},
+	}
+}
+
+func seen(mac string, at time.Time) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state[mac] = deviceState{lastSeen: at, notified: true}
+}
+
+// missed records n consecutive scans that did not find the device.
+func missed(mac string, n int) {
+	for range n {
+		recordMiss(mac)
+	}
+}
+
+func TestEvaluatePeopleAnyDeviceArrives(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetPeople()
+
+	cfg := peopleConfig(config.PresenceAny)
+	now := time.Now()
+
+	if events := evaluatePeople(cfg, now); len(events) != 0 {
+		t.Fatalf("no device seen yet, got %d event(s)", len(events))
+	}
+
+	seen(phoneMac, now)
+	events := evaluatePeople(cfg, now)
+	if len(events) != 1 || !events[0].arrived {
+		t.Fatalf("one device should make the person arrive, got %+v", events)
+	}
+
+	seen(watchMac, now)
+	if events := evaluatePeople(cfg, now); len(events) != 0 {
+		t.Errorf("a second device should not re-announce the person, got %+v", events)
+	}
+}
+
+func TestEvaluatePeopleAllDevicesRequired(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetPeople()
+
+	cfg := peopleConfig(config.PresenceAll)
+	now := time.Now()
+
+	seen(phoneMac, now)
+	if events := evaluatePeople(cfg, now); len(events) != 0 {
+		t.Fatalf("one of two devices should not count as home, got %+v", events)
+	}
+
+	seen(watchMac, now)
+	events := evaluatePeople(cfg, now)
+	if len(events) != 1 || !events[0].arrived {
+		t.Fatalf("all devices present should make the person arrive, got %+v", events)
+	}
+}
+
+func TestEvaluatePeopleDeparture(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetPeople()
+
+	cfg := peopleConfig(config.PresenceAny)
+	now := time.Now()
+
+	seen(phoneMac, now)
+	evaluatePeople(cfg, now)
+
+	// Missed scans alone are not enough within the departure delay.
+	missed(phoneMac, 3)
+	if events := evaluatePeople(cfg, now.Add(5*time.Minute)); len(events) != 0 {
+		t.Fatalf("departure before the delay elapsed: %+v", events)
+	}
+
+	// Past the default 10-minute departure delay with no new sightings.
+	later := now.Add(11 * time.Minute)
+	events := evaluatePeople(cfg, later)
+	if len(events) != 1 || events[0].arrived {
+		t.Fatalf("expected a departure, got %+v", events)
+	}
+
+	// Coming back within the re-notify window is not announced again.
+	seen(phoneMac, later)
+	if events := evaluatePeople(cfg, later); len(events) != 0 {
+		t.Errorf("return within absence_reset_min should not notify, got %+v", events)
+	}
+}
+
+func TestEvaluatePeopleIgnoresDisabled(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetPeople()
+
+	cfg := peopleConfig(config.PresenceAny)
+	cfg.People[0].Enabled = false
+	now := time.Now()
+
+	seen(phoneMac, now)
+	if events := evaluatePeople(cfg, now); len(events) != 0 {
+		t.Errorf("disabled person should not produce events, got %+v", events)
+	}
+}
+
+func TestPersonArrivalHonorsDeviceQuietHours(t *testing.T) {
+	t.Chdir(t.TempDir())
+	resetPause()
+
+	cfg := peopleConfig(config.PresenceAny)
+	cfg.Targets[0].QuietHours = alwaysQuiet(config.QuietDefer)
+	cfg.People[0].Receivers = []config.Receiver{{ID: "person-quiet-1"}}
+
+	onPersonEvent(personEvent{person: cfg.People[0], arrived: true}, cfg)
+	if q := queued("person-quiet-1"); len(q) != 1 || !q[0].Deferred {
+		t.Errorf("person arrival during the device's quiet hours should be deferred, got %+v", q)
+	}
+}
+
+func TestPersonDepartureNeedsAnnouncedArrival(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetPeople()
+
+	cfg := peopleConfig(config.PresenceAny)
+	now := time.Now()
+	seen(phoneMac, now)
+	evaluatePeople(cfg, now) // announced arrival
+
+	later := now.Add(11 * time.Minute)
+	missed(phoneMac, 3)
+	if events := evaluatePeople(cfg, later); len(events) != 1 {
+		t.Fatalf("want the departure after an announced arrival, got %+v", events)
+	}
+
+	// Back within the re-notify window: not announced, so neither is leaving.
+	seen(phoneMac, later)
+	evaluatePeople(cfg, later)
+	missed(phoneMac, 3)
+	if events := evaluatePeople(cfg, later.Add(11*time.Minute)); len(events) != 0 {
+		t.Errorf("departure after an unannounced arrival: %+v", events)
+	}
+}
+
+func TestOnFoundLeavesPersonDevicesToThePerson(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetPause()
+
+	cfg := peopleConfig(config.PresenceAny)
+	cfg.Targets[0].Receivers = []config.Receiver{{ID: "device-of-person-1"}}
+	onFound(cfg.Targets[0], "192.168.0.10", cfg)
+	if q := queued("device-of-person-1"); len(q) != 0 {
+		t.Errorf("a person's device notified its own receivers: %+v", q)
+	}
+}
@@ -0,0 +1,35 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/presence"
+)
+
+var presenceHandler func([]presence.State)
+
+// SetPresenceHandler installs a handler that receives the presence of every
+// enabled target after each scan cycle, e.g. the MQTT publisher.
+func SetPresenceHandler(h func([]presence.State)) {
+	presenceHandler = h
+}
+
+// publishPresence passes every enabled target's presence to the handler.
+func publishPresence(targetsCfg config.TargetsConfig, now time.Time) {
+	if presenceHandler == nil {
+		return
+	}
+	monCfg := config.GetSystemConfig().Monitor
+	var states []presence.State
+
+	stateMu.Lock()
+	for _, t := range targetsCfg.Targets {
+		if t.Enabled {
+			states = append(states, presence.State{Mac: t.Mac, Name: t.Name, Home: presentLocked(t.Mac, monCfg.For(t), now)})
+		}
+	}
+	stateMu.Unlock()
+
+	presenceHandler(states)
+}
@@ -0,0 +1,180 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/arpscan"
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+	"github.com/nekogravitycat/arp-notify/internal/rules"
+)
+
+// targetHome is each target's presence at the end of the previous scan cycle,
+// keyed by the configured MAC, to turn presence into arrival and departure
+// events for the rules. Guarded by stateMu.
+var targetHome = make(map[string]bool)
+
+// RuleResult is the outcome of evaluating one rule in a dry run.
+type RuleResult struct {
+	Name    string              `json:"name"`
+	Matched bool                `json:"matched"`
+	Reason  string              `json:"reason,omitempty"` // why it did not match
+	Actions []config.RuleAction `json:"actions"`          // rendered, only when matched
+}
+
+// targetTransitions compares every enabled target's presence with the previous
+// cycle's and returns the arrivals and departures. A target's first cycle sets
+// the baseline without events, so a restart does not re-announce everyone home.
+// Snoozed targets produce no events.
+func targetTransitions(targetsCfg config.TargetsConfig, hosts []arpscan.Host, now time.Time) []rules.Event {
+	monCfg := config.GetSystemConfig().Monitor
+
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	var events []rules.Event
+	configured := make(map[string]bool)
+	for _, t := range targetsCfg.Targets {
+		if !t.Enabled {
+			continue
+		}
+		configured[t.Mac] = true
+		home := presentLocked(t.Mac, monCfg.For(t), now)
+		was, known := targetHome[t.Mac]
+		targetHome[t.Mac] = home
+		if !known || home == was {
+			// The first cycle after a start (or after the target is added)
+			// only records a baseline, as for the household.
+			continue
+		}
+		if snoozedLocked(t.Mac, now) {
+			continue
+		}
+		trigger := config.TriggerDeparture
+		if home {
+			trigger = config.TriggerArrival
+		}
+		events = append(events, rules.Event{Trigger: trigger, Subject: t.Name, Mac: t.Mac, IP: hostIP(hosts, t.Mac), Time: now})
+	}
+	for mac := range targetHome {
+		if !configured[mac] {
+			delete(targetHome, mac)
+		}
+	}
+	return events
+}
+
+// presenceSnapshot captures who is home, by target name and MAC and by person
+// name, for the rules' present/absent conditions.
+func presenceSnapshot(targetsCfg config.TargetsConfig, now time.Time) rules.Presence {
+	monCfg := config.GetSystemConfig().Monitor
+	home := make(map[string]bool)
+
+	stateMu.Lock()
+	loadOverridesLocked()
+	for _, t := range targetsCfg.Targets {
+		h := presentLocked(t.Mac, monCfg.For(t), now)
+		home[strings.ToLower(t.Name)] = h
+		home[strings.ToLower(t.Mac)] = h
+	}
+	for _, p := range targetsCfg.People {
+		home[strings.ToLower(p.Name)] = people[p.Name].home
+	}
+	stateMu.Unlock()
+
+	return func(name string) (bool, bool) {
+		h, ok := home[strings.ToLower(name)]
+		return h, ok
+	}
+}
+
+// renderAction fills in the action's templates for the event.
+func renderAction(rule config.Rule, a config.RuleAction, ev rules.Event) config.RuleAction {
+	if a.Type == config.ActionNotify {
+		if a.Message == "" {
+			a.Message = fmt.Sprintf("Rule %s: %s %s", rule.Name, strings.ReplaceAll(ev.Trigger, "_", " "), ev.Subject)
+		}
+		a.Message = strings.TrimSpace(rules.Render(a.Message, ev))
+	}
+	if a.Type == config.ActionCommand {
+		args := make([]string, len(a.Command))
+		for i, arg := range a.Command {
+			args[i] = rules.Render(arg, ev)
+		}
+		a.Command = args
+	}
+	return a
+}
+
+// fireRules runs the actions of every rule matching the events. Notify actions
+// honor the global pause like any notification; webhooks and commands are
+// automations and always run.
+func fireRules(targetsCfg config.TargetsConfig, events []rules.Event) {
+	if len(targetsCfg.Rules) == 0 || len(events) == 0 {
+		return
+	}
+	present := presenceSnapshot(targetsCfg, events[0].Time)
+
+	for _, ev := range events {
+		for _, rule := range targetsCfg.Rules {
+			if ok, _ := rules.Match(rule, ev, present); !ok {
+				continue
+			}
+			log.Printf("Rule %q matched %s %s.", rule.Name, ev.Trigger, ev.Subject)
+			for _, a := range rule.Actions {
+				runAction(rule.Name, renderAction(rule, a, ev), ev)
+			}
+		}
+	}
+}
+
+func runAction(rule string, a config.RuleAction, ev rules.Event) {
+	switch a.Type {
+	case config.ActionNotify:
+		about := notifier.Message{Kind: ev.Trigger, Subject: ev.Subject, Mac: ev.Mac, IP: ev.IP, Time: ev.Time}
+		notifyAbout(about, a.Receivers, config.Schedule{}, func(config.Receiver) string { return a.Message })
+	case config.ActionWebhook:
+		go func() {
+			if err := rules.Webhook(context.Background(), rule, a, ev); err != nil {
+				log.Printf("Rule %q: webhook failed: %v", rule, err)
+			}
+		}()
+	case config.ActionCommand:
+		if !config.GetSystemConfig().Rules.AllowCommands {
+			log.Printf("Rule %q: command actions are disabled (rules.allow_commands), skipped.", rule)
+			return
+		}
+		go func() {
+			if err := rules.Command(context.Background(), rule, a, ev); err != nil {
+				log.Printf("Rule %q: command failed: %v", rule, err)
+			}
+		}()
+	}
+}
+
+// DryRunRules evaluates every rule against the event and the current presence
+// without running any action.
+func DryRunRules(ev rules.Event) []RuleResult {
+	targetsCfg := config.GetTargetsConfig()
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	present := presenceSnapshot(targetsCfg, ev.Time)
+
+	out := make([]RuleResult, 0, len(targetsCfg.Rules))
+	for _, rule := range targetsCfg.Rules {
+		ok, reason := rules.Match(rule, ev, present)
+		res := RuleResult{Name: rule.Name, Matched: ok, Reason: reason, Actions: []config.RuleAction{}}
+		if ok {
+			for _, a := range rule.Actions {
+				res.Actions = append(res.Actions, renderAction(rule, a, ev))
+			}
+		}
+		out = append(out, res)
+	}
+	return out
+}
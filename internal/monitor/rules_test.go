@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/rules"
+)
+
+func TestTargetTransitions(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+
+	const mac = "aa:bb:cc:dd:ee:01"
+	cfg := config.TargetsConfig{Targets: []config.Target{{Name: "Phone", Mac: mac, Enabled: true}}}
+	now := time.Now()
+
+	if events := targetTransitions(cfg, nil, now); len(events) != 0 {
+		t.Fatalf("never-seen target produced events: %+v", events)
+	}
+
+	seen(mac, now)
+	events := targetTransitions(cfg, nil, now)
+	if len(events) != 1 || events[0].Trigger != config.TriggerArrival || events[0].Subject != "Phone" {
+		t.Fatalf("want one arrival, got %+v", events)
+	}
+	if events := targetTransitions(cfg, nil, now); len(events) != 0 {
+		t.Fatalf("unchanged presence produced events: %+v", events)
+	}
+
+	seen(mac, now.Add(-2*time.Hour))
+	missed(mac, 10)
+	events = targetTransitions(cfg, nil, now)
+	if len(events) != 1 || events[0].Trigger != config.TriggerDeparture {
+		t.Fatalf("want one departure, got %+v", events)
+	}
+
+	// After a restart a target that is already home only sets the baseline.
+	resetState()
+	seen(mac, now)
+	if events := targetTransitions(cfg, nil, now); len(events) != 0 {
+		t.Fatalf("first cycle after a restart produced events: %+v", events)
+	}
+}
+
+func TestDryRunRules(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetPeople()
+	sys := config.GetSystemConfig()
+	sys.Rules.AllowCommands = true
+	if err := config.SaveSystemConfig(sys); err != nil {
+		t.Fatalf("SaveSystemConfig: %v", err)
+	}
+
+	const mac = "aa:bb:cc:dd:ee:01"
+	rule := config.Rule{
+		Name:    "welcome",
+		Enabled: true,
+		Trigger: config.TriggerArrival,
+		When:    config.RuleConditions{Absent: []string{"Tablet"}},
+		Actions: []config.RuleAction{
+			{Type: config.ActionNotify, Receivers: []config.Receiver{{ID: "U1"}}, Message: "{{.Name}} is home"},
+			{Type: config.ActionCommand, Command: []string{"echo", "{{.Mac}}"}},
+		},
+	}
+	err := config.SaveTargetsConfig(config.TargetsConfig{
+		Targets: []config.Target{
+			{Name: "Phone", Mac: mac, Enabled: true, Detection: config.Detection{Mode: config.ModeBroadcast}},
+			{Name: "Tablet", Mac: "aa:bb:cc:dd:ee:02", Enabled: true, Detection: config.Detection{Mode: config.ModeBroadcast}},
+		},
+		Rules: []config.Rule{rule},
+	})
+	if err != nil {
+		t.Fatalf("SaveTargetsConfig: %v", err)
+	}
+
+	ev := rules.Event{Trigger: config.TriggerArrival, Subject: "Phone", Mac: mac}
+	results := DryRunRules(ev)
+	if len(results) != 1 || !results[0].Matched {
+		t.Fatalf("rule should match, got %+v", results)
+	}
+	actions := results[0].Actions
+	if actions[0].Message != "Phone is home" || actions[1].Command[1] != mac {
+		t.Errorf("actions not rendered: %+v", actions)
+	}
+
+	// The condition reads the current presence.
+	seen("aa:bb:cc:dd:ee:02", time.Now())
+	results = DryRunRules(ev)
+	if results[0].Matched || results[0].Reason == "" {
+		t.Errorf("rule should not match while Tablet is home, got %+v", results[0])
+	}
+}
@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+// fakeArpScan installs a script standing in for arp-scan that prints one
+// tab-separated host line, whatever it is asked.
+func fakeArpScan(t *testing.T, line string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake arp-scan needs a POSIX shell")
+	}
+	bin := filepath.Join(t.TempDir(), "arp-scan")
+	script := "#!/bin/sh\nprintf '" + line + "\\n'\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.GetSystemConfig()
+	cfg.ArpScan.Bin = bin
+	if err := config.SaveSystemConfig(cfg); err != nil {
+		t.Fatalf("SaveSystemConfig: %v", err)
+	}
+}
+
+func saveTargets(t *testing.T, targets ...config.Target) {
+	t.Helper()
+	if err := config.SaveTargetsConfig(config.TargetsConfig{Targets: targets}); err != nil {
+		t.Fatalf("SaveTargetsConfig: %v", err)
+	}
+}
+
+func TestRunScanReportsPerTarget(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetPause()
+	fakeArpScan(t, `192.168.0.7\taa:bb:cc:dd:ee:07\tAcme`)
+	saveTargets(t,
+		config.Target{Name: "Phone", Mac: "AA:BB:CC:DD:EE:07", Enabled: true, Detection: config.Detection{Mode: config.ModeBroadcast}},
+		config.Target{Name: "Tablet", Mac: "aa:bb:cc:dd:ee:08", Enabled: true, Detection: config.Detection{Mode: config.ModeIP, IP: "192.168.0.8"}},
+	)
+
+	results := make(map[string]ScanResult)
+	if err := RunScan("", func(r ScanResult) { results[r.Name] = r }); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := results["Phone"]; !r.Found || r.Method != config.ModeBroadcast || r.IP != "192.168.0.7" {
+		t.Errorf("Phone = %+v, want found by broadcast at 192.168.0.7", r)
+	}
+	if r := results["Tablet"]; r.Found || r.Method != config.ModeIP || r.IP != "192.168.0.8" {
+		t.Errorf("Tablet = %+v, want probed by ip and not found", r)
+	}
+}
+
+func TestRunScanFilter(t *testing.T) {
+	configureMonitor(t, 1440)
+	resetState()
+	resetPause()
+	fakeArpScan(t, `192.168.0.7\taa:bb:cc:dd:ee:07\tAcme`)
+	saveTargets(t,
+		config.Target{Name: "Phone", Mac: "aa:bb:cc:dd:ee:07", Enabled: true, Detection: config.Detection{Mode: config.ModeBroadcast}},
+		config.Target{Name: "Tablet", Mac: "aa:bb:cc:dd:ee:08", Enabled: true, Detection: config.Detection{Mode: config.ModeBroadcast}},
+	)
+
+	var results []ScanResult
+	if err := RunScan("Phone", func(r ScanResult) { results = append(results, r) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Name != "Phone" {
+		t.Errorf("filtered scan results = %+v, want only Phone", results)
+	}
+
+	if err := RunScan("Laptop", nil); err == nil {
+		t.Error("expected error for a filter matching no target")
+	}
+}
+
+func TestRunScanRefusesOverlap(t *testing.T) {
+	configureMonitor(t, 1440)
+
+	scanSemaphore <- struct{}{}
+	defer func() { <-scanSemaphore }()
+
+	if err := RunScan("", nil); !errors.Is(err, ErrScanInProgress) {
+		t.Errorf("RunScan during another scan = %v, want ErrScanInProgress", err)
+	}
+}
@@ -0,0 +1,16 @@
+package monitor
+
+import (
+	"log"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/security"
+)
+
+// onConflict alerts the security receivers of a suspicious IP-to-MAC binding.
+// Like the watchdog's alerts it goes out even while notifications are paused.
+func onConflict(c security.Conflict, cfg config.Security) {
+	message := c.Message()
+	log.Printf("Security: %s", message)
+	deliver(cfg.Receivers, config.Schedule{}, func(config.Receiver) string { return message })
+}
@@ -9,7 +9,22 @@ import (
 
 type deviceState struct {
 	lastSeen time.Time
+	misses   int // consecutive completed scans that did not find the device
 	notified bool
+	quieted  bool // the last notification was held back by quiet hours
+
+	awayAlerted bool // away_alert_hours was reported for the current absence
+}
+
+// present reports whether the device still counts as home under the policy:
+// it departs only after missing miss_threshold scans in a row and staying
+// unseen for departure_delay_min.
+func (ds deviceState) present(policy config.MonitorConfig, now time.Time) bool {
+	if ds.lastSeen.IsZero() {
+		return false
+	}
+	departAfter := time.Duration(policy.DepartureDelayMin) * time.Minute
+	return ds.misses < policy.MissThreshold || now.Sub(ds.lastSeen) < departAfter
 }
 
 var (
@@ -21,53 +36,127 @@ var (
 type DeviceStatus struct {
 	Mac      string    `json:"mac"`
 	LastSeen time.Time `json:"lastSeen"`
+	Present  bool      `json:"present"`
 	Notified bool      `json:"notified"`
+	Quieted  bool      `json:"quieted"`
+	Override *Override `json:"override,omitempty"`
 }
 
 // Snapshot returns the current device states for the status view.
 func Snapshot() []DeviceStatus {
+	monCfg := config.GetSystemConfig().Monitor
+	targetByMac := make(map[string]config.Target)
+	for _, t := range config.GetTargetsConfig().Targets {
+		targetByMac[t.Mac] = t
+	}
+	now := time.Now()
+
 	stateMu.Lock()
 	defer stateMu.Unlock()
+	loadOverridesLocked()
 
 	out := make([]DeviceStatus, 0, len(state))
 	for mac, ds := range state {
-		out = append(out, DeviceStatus{
+		s := DeviceStatus{
 			Mac:      mac,
 			LastSeen: ds.lastSeen,
+			Present:  presentLocked(mac, monCfg.For(targetByMac[mac]), now),
 			Notified: ds.notified,
-		})
+			Quieted:  ds.quieted,
+		}
+		if o, ok := overrideLocked(mac, now); ok {
+			s.Override = &o
+		}
+		out = append(out, s)
+	}
+
+	// Overridden targets that were never seen are listed too, so the override
+	// shows up in the status view.
+	for key, o := range overrides {
+		if _, seen := state[o.Mac]; seen {
+			continue
+		}
+		if _, ok := overrideLocked(key, now); ok {
+			out = append(out, DeviceStatus{
+				Mac:      o.Mac,
+				Present:  presentLocked(o.Mac, monCfg.For(targetByMac[o.Mac]), now),
+				Override: &o,
+			})
+		}
 	}
 	return out
 }
 
-// updateStateAndShouldNotify records a sighting of the given MAC and atomically
-// decides whether a notification should be sent. When it returns true it has
-// already marked the device as notified, so callers need no second step.
-func updateStateAndShouldNotify(mac string) bool {
-	cfg := config.GetSystemConfig()
+// updateStateAndShouldNotify records a sighting of the target and atomically
+// decides whether a notification should be sent, using the target's re-notify
+// window and any manual override. When it returns true it has already marked
+// the device as notified, so callers need no second step.
+func updateStateAndShouldNotify(target config.Target) bool {
+	policy := config.GetSystemConfig().Monitor.For(target)
+	mac := target.Mac
 
 	stateMu.Lock()
 	defer stateMu.Unlock()
 
 	now := time.Now()
 
-	ds, exists := state[mac]
-	if !exists {
-		// First sighting: notify and mark as notified in one go.
-		state[mac] = deviceState{lastSeen: now, notified: true}
-		return true
-	}
+	// A first sighting starts from the zero state, which always notifies.
+	ds := state[mac]
 
 	// Reset notified status if last seen was long ago.
-	if time.Since(ds.lastSeen) > time.Duration(cfg.Monitor.AbsenceResetMin)*time.Minute {
+	if now.Sub(ds.lastSeen) > time.Duration(policy.AbsenceResetMin)*time.Minute {
 		ds.notified = false
 	}
 
 	shouldNotify := !ds.notified
 	ds.lastSeen = now
+	ds.misses = 0
+	ds.awayAlerted = false
+
+	if o, ok := overrideLocked(mac, now); ok && shouldNotify {
+		switch {
+		case o.forcing(now) && o.Presence == ForceAbsent:
+			// Held away manually: keep the arrival pending until the override ends.
+			shouldNotify = false
+		case o.forcing(now), o.snoozed(now):
+			// Already known to be home, or snoozed: record the arrival as announced.
+			ds.notified = true
+			shouldNotify = false
+		}
+	}
+
 	if shouldNotify {
 		ds.notified = true
 	}
 	state[mac] = ds
 	return shouldNotify
 }
+
+// lastSeen returns when the device was last seen, zero if never.
+func lastSeen(mac string) time.Time {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	return state[mac].lastSeen
+}
+
+// recordMiss counts a completed scan that did not find a known device.
+func recordMiss(mac string) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if ds, ok := state[mac]; ok {
+		ds.misses++
+		state[mac] = ds
+	}
+}
+
+// markQuieted records whether the latest notification for the MAC was held
+// back by quiet hours. The sighting itself is recorded either way, so a
+// suppressed arrival is not announced again once the window ends.
+func markQuieted(mac string, quieted bool) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if ds, ok := state[mac]; ok {
+		ds.quieted = quieted
+		state[mac] = ds
+	}
+}
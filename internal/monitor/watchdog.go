@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+// watchdogTick is how often the watchdog checks that scan cycles still run.
+const watchdogTick = 15 * time.Second
+
+// cycleHealth summarizes the arp-scan runs of one scan cycle.
+type cycleHealth struct {
+	runs           int
+	failures       int
+	lastErr        error
+	emptyBroadcast bool // the broadcast scan succeeded but no host answered
+}
+
+func (h *cycleHealth) record(err error) {
+	h.runs++
+	if err != nil {
+		h.failures++
+		h.lastErr = err
+	}
+}
+
+// problem describes why the cycle counts as failed, or "" if it didn't fail.
+// A cycle without any scan (nothing to look for) is fine.
+func (h cycleHealth) problem() string {
+	switch {
+	case h.runs > 0 && h.failures == h.runs:
+		return "arp-scan failed: " + h.lastErr.Error()
+	case h.emptyBroadcast:
+		return "the broadcast scan found no hosts"
+	}
+	return ""
+}
+
+// WatchdogStatus is the scan watchdog's view, exposed to the web UI.
+type WatchdogStatus struct {
+	Healthy   bool      `json:"healthy"`
+	Failures  int       `json:"failures"` // consecutive failed or empty cycles
+	LastCycle time.Time `json:"lastCycle"`
+	Problem   string    `json:"problem,omitempty"`
+}
+
+var (
+	watchdogMu sync.Mutex
+	watchdog   struct {
+		failures  int
+		lastCycle time.Time
+		problem   string
+		alerted   bool // admins were told and are owed a recovery notice
+	}
+)
+
+// WatchdogSnapshot returns the watchdog state for the status view.
+func WatchdogSnapshot() WatchdogStatus {
+	watchdogMu.Lock()
+	defer watchdogMu.Unlock()
+	return WatchdogStatus{
+		Healthy:   watchdog.problem == "",
+		Failures:  watchdog.failures,
+		LastCycle: watchdog.lastCycle,
+		Problem:   watchdog.problem,
+	}
+}
+
+// recordCycle feeds one finished scan cycle to the watchdog. Admins are alerted
+// once failed_cycles cycles in a row failed, and told when scans recover.
+func recordCycle(h cycleHealth, admins []config.Receiver, now time.Time) {
+	threshold := config.GetSystemConfig().Watchdog.FailedCycles
+
+	watchdogMu.Lock()
+	watchdog.lastCycle = now
+	var message string
+	if p := h.problem(); p != "" {
+		watchdog.failures++
+		watchdog.problem = fmt.Sprintf("%d scan cycle(s) in a row failed: %s", watchdog.failures, p)
+		if watchdog.failures >= threshold && !watchdog.alerted {
+			watchdog.alerted = true
+			message = "arp-notify: " + watchdog.problem + ". Presence notifications may be missing."
+		}
+	} else {
+		if watchdog.alerted {
+			message = "arp-notify: scans are working again."
+		}
+		watchdog.failures, watchdog.problem, watchdog.alerted = 0, "", false
+	}
+	watchdogMu.Unlock()
+
+	if message != "" {
+		alertAdmins(admins, message)
+	}
+}
+
+// checkStalled alerts the admins when no scan cycle has finished within twice
+// the scan interval. started stands in for the last cycle until the first one
+// finishes.
+func checkStalled(admins []config.Receiver, interval time.Duration, started, now time.Time) {
+	watchdogMu.Lock()
+	last := watchdog.lastCycle
+	if last.IsZero() {
+		last = started
+	}
+	var message string
+	if now.Sub(last) > 2*interval && !watchdog.alerted {
+		watchdog.alerted = true
+		watchdog.problem = fmt.Sprintf("no scan cycle finished since %s", last.Format(time.DateTime))
+		message = "arp-notify: " + watchdog.problem + " (interval " + interval.String() + ")."
+	}
+	watchdogMu.Unlock()
+
+	if message != "" {
+		alertAdmins(admins, message)
+	}
+}
+
+// StartWatchdog checks that the scan loop keeps running until ctx is done.
+// Failed and empty cycles are reported by the scan loop itself.
+func StartWatchdog(ctx context.Context) {
+	started := time.Now()
+	ticker := time.NewTicker(watchdogTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			interval := time.Duration(config.GetSystemConfig().ArpScan.IntervalSec) * time.Second
+			checkStalled(config.GetTargetsConfig().Admins, interval, started, now)
+		}
+	}
+}
+
+// alertAdmins sends a watchdog message to the admins. It bypasses the global
+// pause: a broken scanner is worth knowing about even on vacation.
+func alertAdmins(admins []config.Receiver, message string) {
+	log.Printf("Watchdog: %s", message)
+	if len(admins) == 0 {
+		log.Println("Watchdog: no admins configured in targets.yaml, alert not sent.")
+		return
+	}
+	deliver(admins, config.Schedule{}, func(config.Receiver) string { return message })
+}
@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func resetWatchdog() {
+	watchdogMu.Lock()
+	defer watchdogMu.Unlock()
+	watchdog.failures, watchdog.lastCycle, watchdog.problem, watchdog.alerted = 0, time.Time{}, "", false
+}
+
+func watchdogAlerted() bool {
+	watchdogMu.Lock()
+	defer watchdogMu.Unlock()
+	return watchdog.alerted
+}
+
+func TestCycleHealthProblem(t *testing.T) {
+	var ok cycleHealth
+	ok.record(nil)
+	ok.record(errors.New("timeout"))
+	if p := ok.problem(); p != "" {
+		t.Errorf("a cycle with one successful scan should be fine, got %q", p)
+	}
+
+	var failed cycleHealth
+	failed.record(errors.New("permission denied"))
+	if p := failed.problem(); p != "arp-scan failed: permission denied" {
+		t.Errorf("problem = %q", p)
+	}
+
+	if p := (cycleHealth{runs: 1, emptyBroadcast: true}).problem(); p == "" {
+		t.Error("an empty broadcast scan should count as failed")
+	}
+	if p := (cycleHealth{}).problem(); p != "" {
+		t.Errorf("a cycle without scans should be fine, got %q", p)
+	}
+}
+
+func TestRecordCycleAlertsAfterThresholdAndRecovers(t *testing.T) {
+	configureMonitor(t, 1440) // failed_cycles defaults to 3
+	resetWatchdog()
+
+	bad := cycleHealth{runs: 1, failures: 1, lastErr: errors.New("permission denied")}
+	now := time.Now()
+	recordCycle(bad, nil, now)
+	recordCycle(bad, nil, now)
+	if watchdogAlerted() {
+		t.Fatal("alerted before failed_cycles was reached")
+	}
+	recordCycle(bad, nil, now)
+	if !watchdogAlerted() {
+		t.Fatal("expected an alert after 3 failed cycles")
+	}
+	if s := WatchdogSnapshot(); s.Healthy || s.Failures != 3 {
+		t.Errorf("snapshot = %+v, want unhealthy with 3 failures", s)
+	}
+
+	recordCycle(cycleHealth{runs: 1}, nil, now)
+	if s := WatchdogSnapshot(); !s.Healthy || s.Failures != 0 || watchdogAlerted() {
+		t.Errorf("a good cycle should recover, got %+v", s)
+	}
+}
+
+func TestCheckStalled(t *testing.T) {
+	resetWatchdog()
+
+	started := time.Now()
+	checkStalled(nil, time.Minute, started, started.Add(90*time.Second))
+	if watchdogAlerted() {
+		t.Fatal("alerted within 2x the interval")
+	}
+	checkStalled(nil, time.Minute, started, started.Add(3*time.Minute))
+	if !watchdogAlerted() || WatchdogSnapshot().Healthy {
+		t.Error("expected a stall alert after 2x the interval")
+	}
+}
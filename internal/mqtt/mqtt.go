@@ -0,0 +1,336 @@
+// Package mqtt publishes every target's presence to an MQTT broker as
+// retained "home"/"not_home" states, with an availability topic backed by the
+// connection's last will and Home Assistant discovery configs so each target
+// shows up as a device_tracker. It speaks just enough MQTT 3.1.1 (QoS 0
+// publishes) for that, and republishes everything after a reconnect.
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/presence"
+)
+
+// Payloads, matching Home Assistant's device_tracker defaults.
+const (
+	payloadHome    = "home"
+	payloadNotHome = "not_home"
+	payloadOnline  = "online"
+	payloadOffline = "offline"
+)
+
+const (
+	keepAlive    = 60 * time.Second
+	ioTimeout    = 15 * time.Second
+	configPoll   = 30 * time.Second // how often a disabled publisher rechecks the config
+	maxBackoff   = 2 * time.Minute
+	healthyAfter = 5 * time.Minute // a connection this old resets the backoff
+)
+
+// firstBackoff is the wait before the first reconnect; it doubles after each
+// failure. A variable so tests can shorten it.
+var firstBackoff = time.Second
+
+// errConfigChanged ends a session so the next one uses the new settings.
+var errConfigChanged = errors.New("mqtt config changed")
+
+// Publisher keeps a connection to the broker in config.yaml and publishes the
+// latest target states. It reads the config on every (re)connect and
+// reconnects when it changes.
+type Publisher struct {
+	config    func() config.MQTTConfig
+	password  func() string
+	tlsConfig func(host string) *tls.Config
+
+	mu     sync.Mutex
+	states []presence.State
+	wake   chan struct{}
+}
+
+// New returns a publisher reading its settings from cfg and the password
+// from MQTT_PASSWORD.
+func New(cfg func() config.MQTTConfig) *Publisher {
+	return &Publisher{
+		config:    cfg,
+		password:  func() string { return os.Getenv("MQTT_PASSWORD") },
+		tlsConfig: func(host string) *tls.Config { return &tls.Config{ServerName: host} },
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Update replaces the published states. Targets missing from states have
+// their retained topics cleared if this connection published them; topics of
+// targets removed while arp-notify was stopped or disconnected stay on the
+// broker until cleared by hand.
+func (p *Publisher) Update(states []presence.State) {
+	p.mu.Lock()
+	p.states = slices.Clone(states)
+	p.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default: // a pending wake-up is already queued; coalesce.
+	}
+}
+
+// Run keeps a session with the broker until ctx is done, reconnecting with
+// exponential backoff when the connection drops.
+func (p *Publisher) Run(ctx context.Context) {
+	backoff := firstBackoff
+	for ctx.Err() == nil {
+		cfg := p.config()
+		if !cfg.Enabled() {
+			select {
+			case <-ctx.Done():
+			case <-p.wake:
+			case <-time.After(configPoll):
+			}
+			continue
+		}
+
+		start := time.Now()
+		err := p.session(ctx, cfg)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, errConfigChanged):
+			log.Println("MQTT config changed, reconnecting.")
+			backoff = firstBackoff
+			continue
+		case time.Since(start) > healthyAfter:
+			backoff = firstBackoff
+		}
+		log.Printf("MQTT connection to %s failed: %v; retrying in %s.", cfg.Broker, err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// session connects, publishes everything and then keeps the broker up to
+// date until the connection fails, the config changes or ctx is done.
+func (p *Publisher) session(ctx context.Context, raw config.MQTTConfig) error {
+	cfg := raw.WithDefaults()
+	conn, err := p.dial(ctx, cfg.Broker)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	write := func(pk packet) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(ioTimeout))
+		_, err := conn.Write(pk.encode())
+		return err
+	}
+	r := bufio.NewReader(conn)
+
+	err = write(connectPacket(connectOptions{
+		clientID:     cfg.ClientID,
+		username:     cfg.Username,
+		password:     p.password(),
+		keepAliveSec: uint16(keepAlive / time.Second),
+		will:         &will{topic: cfg.AvailabilityTopic, payload: payloadOffline, retain: true},
+	}))
+	if err != nil {
+		return err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(ioTimeout))
+	ack, err := readPacket(r)
+	if err != nil {
+		return fmt.Errorf("waiting for CONNACK: %w", err)
+	}
+	if ack.kind() != typeConnack || len(ack.body) != 2 {
+		return fmt.Errorf("unexpected packet type %d instead of CONNACK", ack.kind())
+	}
+	if rc := ack.body[1]; rc != 0 {
+		return fmt.Errorf("connection refused: %s", connackErrors[rc])
+	}
+	log.Printf("Connected to MQTT broker %s.", cfg.Broker)
+
+	// The reader only has to notice the connection dying: the broker sends
+	// nothing but PINGRESPs to a client that subscribes to nothing.
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+			if _, err := readPacket(r); err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	// goodbye marks the publisher offline, which the will would not do on a
+	// clean disconnect.
+	goodbye := func() {
+		_ = write(publishPacket(cfg.AvailabilityTopic, []byte(payloadOffline), true))
+		_ = write(packet{typeDisconnect << 4, nil})
+	}
+
+	if err := write(publishPacket(cfg.AvailabilityTopic, []byte(payloadOnline), true)); err != nil {
+		return err
+	}
+	sent := make(map[string][]byte) // topic -> retained payload published in this session
+	ping := time.NewTicker(keepAlive / 2)
+	defer ping.Stop()
+	for {
+		if err := p.sync(cfg, sent, write); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			goodbye()
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case <-ping.C:
+			if err := write(packet{typePingreq << 4, nil}); err != nil {
+				return err
+			}
+		case <-p.wake:
+		}
+		if p.config() != raw {
+			goodbye()
+			return errConfigChanged
+		}
+	}
+}
+
+func (p *Publisher) dial(ctx context.Context, broker string) (net.Conn, error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return nil, err
+	}
+	secure := u.Scheme == "tls" || u.Scheme == "mqtts"
+	addr := u.Host
+	if u.Port() == "" {
+		port := "1883"
+		if secure {
+			port = "8883"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	dialer := &net.Dialer{Timeout: ioTimeout}
+	if secure {
+		return (&tls.Dialer{NetDialer: dialer, Config: p.tlsConfig(u.Hostname())}).DialContext(ctx, "tcp", addr)
+	}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// sync publishes the retained messages that differ from what this session
+// already sent, and clears those it sent for targets that are gone.
+func (p *Publisher) sync(cfg config.MQTTConfig, sent map[string][]byte, write func(packet) error) error {
+	p.mu.Lock()
+	msgs := messages(cfg, p.states)
+	p.mu.Unlock()
+
+	want := make(map[string]bool, len(msgs))
+	for _, m := range msgs {
+		want[m.topic] = true
+		if old, ok := sent[m.topic]; ok && bytes.Equal(old, m.payload) {
+			continue
+		}
+		if err := write(publishPacket(m.topic, m.payload, true)); err != nil {
+			return err
+		}
+		sent[m.topic] = m.payload
+	}
+	for topic := range sent {
+		if !want[topic] {
+			// An empty retained message deletes the retained one; for a
+			// discovery topic it also removes the entity from Home Assistant.
+			if err := write(publishPacket(topic, nil, true)); err != nil {
+				return err
+			}
+			delete(sent, topic)
+		}
+	}
+	return nil
+}
+
+type message struct {
+	topic   string
+	payload []byte
+}
+
+// discoveryConfig is a Home Assistant MQTT device_tracker config.
+type discoveryConfig struct {
+	Name              *string         `json:"name"` // null: the entity takes the device's name
+	UniqueID          string          `json:"unique_id"`
+	StateTopic        string          `json:"state_topic"`
+	PayloadHome       string          `json:"payload_home"`
+	PayloadNotHome    string          `json:"payload_not_home"`
+	SourceType        string          `json:"source_type"`
+	AvailabilityTopic string          `json:"availability_topic"`
+	Device            discoveryDevice `json:"device"`
+}
+
+type discoveryDevice struct {
+	Identifiers []string    `json:"identifiers"`
+	Name        string      `json:"name"`
+	Connections [][2]string `json:"connections"`
+}
+
+// topicID is how a target appears in topics: its MAC without separators.
+func topicID(mac string) string {
+	return strings.NewReplacer(":", "", "-", "").Replace(strings.ToLower(mac))
+}
+
+// messages returns the retained messages describing the states, each target's
+// discovery config (if enabled) before its state.
+func messages(cfg config.MQTTConfig, states []presence.State) []message {
+	tmpl, err := cfg.StateTopicTemplate()
+	if err != nil {
+		log.Printf("MQTT state topic: %v", err) // checked by config validation
+		return nil
+	}
+	var out []message
+	for _, s := range states {
+		id := topicID(s.Mac)
+		var topic strings.Builder
+		if err := tmpl.Execute(&topic, struct{ ID, Name string }{id, s.Name}); err != nil {
+			log.Printf("MQTT state topic for %s: %v", s.Name, err)
+			continue
+		}
+		if cfg.Discovery {
+			uid := "arp_notify_" + id
+			data, _ := json.Marshal(discoveryConfig{
+				UniqueID:          uid,
+				StateTopic:        topic.String(),
+				PayloadHome:       payloadHome,
+				PayloadNotHome:    payloadNotHome,
+				SourceType:        "router",
+				AvailabilityTopic: cfg.AvailabilityTopic,
+				Device: discoveryDevice{
+					Identifiers: []string{uid},
+					Name:        s.Name,
+					Connections: [][2]string{{"mac", strings.ToLower(s.Mac)}},
+				},
+			})
+			out = append(out, message{cfg.DiscoveryPrefix + "/device_tracker/" + uid + "/config", data})
+		}
+		payload := payloadNotHome
+		if s.Home {
+			payload = payloadHome
+		}
+		out = append(out, message{topic.String(), []byte(payload)})
+	}
+	return out
+}
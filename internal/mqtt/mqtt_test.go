@@ -0,0 +1,255 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/presence"
+)
+
+// fakeBroker is a minimal in-process MQTT broker that keeps retained
+// messages and records how clients connected.
+type fakeBroker struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	conns    []net.Conn
+	connects int
+	username string
+	password string
+	will     will
+	retained map[string]string
+}
+
+func startBroker(t *testing.T) *fakeBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{ln: ln, retained: make(map[string]string)}
+	t.Cleanup(func() {
+		ln.Close()
+		b.drop()
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns = append(b.conns, conn)
+			b.mu.Unlock()
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *fakeBroker) url() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+// drop closes every client connection without a DISCONNECT, as a broker
+// restart would, publishing the wills.
+func (b *fakeBroker) drop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.conns {
+		c.Close()
+	}
+	b.conns = nil
+	if b.will.topic != "" {
+		b.retained[b.will.topic] = b.will.payload
+	}
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		pk, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		switch pk.kind() {
+		case typeConnect:
+			b.connects++
+			b.parseConnect(pk.body)
+			_, _ = conn.Write(packet{typeConnack << 4, []byte{0, 0}}.encode())
+		case typePublish:
+			topic, payload, _ := readString(pk.body)
+			if len(payload) == 0 {
+				delete(b.retained, topic)
+			} else {
+				b.retained[topic] = string(payload)
+			}
+		case typePingreq:
+			_, _ = conn.Write(packet{typePingresp << 4, nil}.encode())
+		case typeDisconnect:
+			b.will = will{}
+			conn.Close()
+		}
+		b.mu.Unlock()
+	}
+}
+
+func (b *fakeBroker) parseConnect(body []byte) {
+	_, rest, _ := readString(body) // protocol name
+	flags := rest[1]
+	rest = rest[4:]               // level, flags, keep alive
+	_, rest, _ = readString(rest) // client ID
+	b.will = will{}
+	if flags&flagWill != 0 {
+		b.will.retain = flags&flagWillRetain != 0
+		b.will.topic, rest, _ = readString(rest)
+		b.will.payload, rest, _ = readString(rest)
+	}
+	if flags&flagUsername != 0 {
+		b.username, rest, _ = readString(rest)
+	}
+	if flags&flagPassword != 0 {
+		b.password, _, _ = readString(rest)
+	}
+}
+
+func (b *fakeBroker) get(topic string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.retained[topic]
+	return v, ok
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func startPublisher(t *testing.T, cfg config.MQTTConfig) (*Publisher, context.CancelFunc) {
+	p := New(func() config.MQTTConfig { return cfg })
+	p.password = func() string { return "pw" }
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return p, cancel
+}
+
+const (
+	stateTopic     = "arp-notify/aabbccddeeff/state"
+	discoveryTopic = "homeassistant/device_tracker/arp_notify_aabbccddeeff/config"
+)
+
+func TestPublishesStatesAndDiscovery(t *testing.T) {
+	b := startBroker(t)
+	p, _ := startPublisher(t, config.MQTTConfig{Broker: b.url(), Username: "home", Discovery: true})
+	p.Update([]presence.State{
+		{Mac: "AA:BB:CC:DD:EE:FF", Name: "Mom's phone", Home: true},
+		{Mac: "11:22:33:44:55:66", Name: "Laptop"},
+	})
+
+	waitFor(t, "states", func() bool {
+		v, _ := b.get("arp-notify/112233445566/state")
+		return v == payloadNotHome
+	})
+	if v, _ := b.get(stateTopic); v != payloadHome {
+		t.Errorf("state = %q, want home", v)
+	}
+	if v, _ := b.get(config.DefaultMQTTAvailability); v != payloadOnline {
+		t.Errorf("availability = %q, want online", v)
+	}
+	b.mu.Lock()
+	if b.will != (will{config.DefaultMQTTAvailability, payloadOffline, true}) || b.username != "home" || b.password != "pw" {
+		t.Errorf("connect: will %+v, user %q, password %q", b.will, b.username, b.password)
+	}
+	b.mu.Unlock()
+
+	raw, _ := b.get(discoveryTopic)
+	var dc discoveryConfig
+	if err := json.Unmarshal([]byte(raw), &dc); err != nil {
+		t.Fatalf("discovery config %q: %v", raw, err)
+	}
+	if dc.Name != nil || dc.StateTopic != stateTopic || dc.AvailabilityTopic != config.DefaultMQTTAvailability ||
+		dc.Device.Name != "Mom's phone" || dc.Device.Connections[0] != [2]string{"mac", "aa:bb:cc:dd:ee:ff"} {
+		t.Errorf("discovery config = %s", raw)
+	}
+
+	// Mom leaves and the laptop is no longer tracked.
+	p.Update([]presence.State{{Mac: "AA:BB:CC:DD:EE:FF", Name: "Mom's phone"}})
+	waitFor(t, "departure and removal", func() bool {
+		v, _ := b.get(stateTopic)
+		_, laptop := b.get("homeassistant/device_tracker/arp_notify_112233445566/config")
+		return v == payloadNotHome && !laptop
+	})
+}
+
+func TestReconnectsAndRepublishes(t *testing.T) {
+	firstBackoff = 10 * time.Millisecond
+	t.Cleanup(func() { firstBackoff = time.Second })
+
+	b := startBroker(t)
+	p, cancel := startPublisher(t, config.MQTTConfig{Broker: b.url(), StateTopic: "home/{{.Name}}"})
+	p.Update([]presence.State{{Mac: "aa:bb:cc:dd:ee:ff", Name: "phone", Home: true}})
+	waitFor(t, "first state", func() bool {
+		v, _ := b.get("home/phone")
+		return v == payloadHome
+	})
+
+	// A broker restart loses the retained state and publishes the will.
+	b.drop()
+	b.mu.Lock()
+	delete(b.retained, "home/phone")
+	b.mu.Unlock()
+
+	waitFor(t, "republish after reconnect", func() bool {
+		v, _ := b.get("home/phone")
+		online, _ := b.get(config.DefaultMQTTAvailability)
+		return v == payloadHome && online == payloadOnline
+	})
+	b.mu.Lock()
+	if b.connects != 2 {
+		t.Errorf("connects = %d, want 2", b.connects)
+	}
+	b.mu.Unlock()
+	if _, ok := b.get(discoveryTopic); ok {
+		t.Error("discovery published although disabled")
+	}
+
+	cancel()
+	waitFor(t, "offline on shutdown", func() bool {
+		v, _ := b.get(config.DefaultMQTTAvailability)
+		return v == payloadOffline
+	})
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	payload := make([]byte, 300) // needs a two-byte remaining length
+	pk := publishPacket("t", payload, true)
+	got, err := readPacket(bufio.NewReader(bytes.NewReader(pk.encode())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.header != 0x31 || len(got.body) != 2+1+300 || binary.BigEndian.Uint16(got.body) != 1 {
+		t.Errorf("round trip: header %#x, %d bytes", got.header, len(got.body))
+	}
+}
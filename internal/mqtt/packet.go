@@ -0,0 +1,164 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// MQTT 3.1.1 control packet types, as the high nibble of the first byte.
+const (
+	typeConnect    = 1
+	typeConnack    = 2
+	typePublish    = 3
+	typePingreq    = 12
+	typePingresp   = 13
+	typeDisconnect = 14
+)
+
+// Connect flags.
+const (
+	flagCleanSession = 0x02
+	flagWill         = 0x04
+	flagWillRetain   = 0x20
+	flagPassword     = 0x40
+	flagUsername     = 0x80
+)
+
+// packet is a control packet: its first byte and everything after the
+// remaining length.
+type packet struct {
+	header byte
+	body   []byte
+}
+
+func (p packet) kind() byte {
+	return p.header >> 4
+}
+
+func (p packet) encode() []byte {
+	out := []byte{p.header}
+	n := len(p.body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(out, p.body...)
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	var n, mult int
+	for i := 0; ; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		n += int(b&0x7f) << mult
+		mult += 7
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return packet{}, errors.New("malformed remaining length")
+		}
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{header, body}, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// readString reads a length-prefixed string, returning it and the rest.
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("short string")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errors.New("short string")
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+// will is the message the broker publishes when the connection drops.
+type will struct {
+	topic, payload string
+	retain         bool
+}
+
+type connectOptions struct {
+	clientID           string
+	username, password string
+	keepAliveSec       uint16
+	will               *will
+}
+
+func connectPacket(o connectOptions) packet {
+	body := appendString(nil, "MQTT")
+	body = append(body, 4) // protocol level 3.1.1
+
+	flags := byte(flagCleanSession)
+	if o.will != nil {
+		flags |= flagWill
+		if o.will.retain {
+			flags |= flagWillRetain
+		}
+	}
+	if o.username != "" {
+		flags |= flagUsername
+		if o.password != "" {
+			flags |= flagPassword
+		}
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, o.keepAliveSec)
+
+	body = appendString(body, o.clientID)
+	if o.will != nil {
+		body = appendString(body, o.will.topic)
+		body = appendString(body, o.will.payload)
+	}
+	if o.username != "" {
+		body = appendString(body, o.username)
+		if o.password != "" {
+			body = appendString(body, o.password)
+		}
+	}
+	return packet{typeConnect << 4, body}
+}
+
+// publishPacket is a QoS 0 publish.
+func publishPacket(topic string, payload []byte, retain bool) packet {
+	header := byte(typePublish << 4)
+	if retain {
+		header |= 0x01
+	}
+	return packet{header, append(appendString(nil, topic), payload...)}
+}
+
+// connackErrors describes the CONNACK return codes other than 0 (accepted).
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
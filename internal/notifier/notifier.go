@@ -0,0 +1,84 @@
+// Package notifier delivers messages through a registry of configured
+// channels. A receiver ID names its channel as a prefix ("line:U…"); see
+// config.ParseReceiverID.
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+// Message is one notification. It is defined in config so that the webhook
+// and email templates, which are rendered with it, can be test-rendered when
+// the config is validated.
+type Message = config.Notification
+
+// Notifier sends a message to an address on one delivery channel.
+type Notifier interface {
+	Send(ctx context.Context, address string, msg Message) error
+}
+
+// Configurable is implemented by notifiers whose settings may be empty, such
+// as email without an SMTP host. Such a channel counts as configured only
+// while Configured reports true.
+type Configurable interface {
+	Configured() bool
+}
+
+// ErrUnknownChannel is returned for receivers on a channel that is not
+// configured.
+var ErrUnknownChannel = errors.New("channel not configured")
+
+// ErrPermanent marks a delivery error that retrying will not fix, such as a
+// webhook rejecting the request. Channels wrap it; the outbox gives up on it
+// right away. Channels send once and leave retries to the outbox.
+var ErrPermanent = errors.New("permanent failure")
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]Notifier) // channel name -> notifier
+)
+
+// Register makes a channel available for delivery, replacing any notifier
+// already registered under the name.
+func Register(channel string, n Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[channel] = n
+}
+
+// Channels returns the names of the configured channels, sorted.
+func Channels() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]string, 0, len(registry))
+	for name, n := range registry {
+		if configured(n) {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func configured(n Notifier) bool {
+	c, ok := n.(Configurable)
+	return !ok || c.Configured()
+}
+
+// Send delivers the message to the receiver ID through its channel.
+func Send(ctx context.Context, receiverID string, msg Message) error {
+	channel, address := config.ParseReceiverID(receiverID)
+	mu.RLock()
+	n, ok := registry[channel]
+	mu.RUnlock()
+	if !ok || !configured(n) {
+		return fmt.Errorf("%s: %w", channel, ErrUnknownChannel)
+	}
+	return n.Send(ctx, address, msg)
+}
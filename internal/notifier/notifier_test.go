@@ -0,0 +1,52 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+type recorder struct{ to, message string }
+
+func (r *recorder) Send(_ context.Context, to string, msg Message) error {
+	r.to, r.message = to, msg.Text
+	return nil
+}
+
+type unconfigured struct{ recorder }
+
+func (unconfigured) Configured() bool { return false }
+
+func TestSendRoutesByChannel(t *testing.T) {
+	rec := &recorder{}
+	Register(config.ChannelLine, rec)
+	t.Cleanup(func() { Register(config.ChannelLine, &unconfigured{}) })
+
+	for _, id := range []string{"U1", "line:U1"} {
+		*rec = recorder{}
+		if err := Send(context.Background(), id, Message{Text: "hi"}); err != nil {
+			t.Fatalf("Send(%q): %v", id, err)
+		}
+		if rec.to != "U1" || rec.message != "hi" {
+			t.Errorf("Send(%q) delivered %+v", id, rec)
+		}
+	}
+	if !slices.Contains(Channels(), config.ChannelLine) {
+		t.Error("line should be configured")
+	}
+
+	if err := Send(context.Background(), "telegram:1", Message{Text: "hi"}); !errors.Is(err, ErrUnknownChannel) {
+		t.Errorf("unregistered channel: got %v, want ErrUnknownChannel", err)
+	}
+
+	Register(config.ChannelLine, &unconfigured{})
+	if slices.Contains(Channels(), config.ChannelLine) {
+		t.Error("line should not be configured")
+	}
+	if err := Send(context.Background(), "U1", Message{Text: "hi"}); !errors.Is(err, ErrUnknownChannel) {
+		t.Errorf("unconfigured channel: got %v, want ErrUnknownChannel", err)
+	}
+}
@@ -0,0 +1,324 @@
+// Package outbox queues notifications on disk and delivers them with retries,
+// so a channel that is briefly unreachable does not lose them. Notifications
+// held back for quiet hours or a batching window wait here too, so a restart
+// does not lose them either.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+	"github.com/nekogravitycat/arp-notify/internal/statefile"
+)
+
+const (
+	outboxPath  = "outbox.json"
+	sendTimeout = 30 * time.Second // bounds one delivery attempt
+	maxAttempts = 10
+	maxBackoff  = 30 * time.Minute
+	maxFailed   = 100 // failed notifications kept for the web UI
+)
+
+// firstBackoff is the wait before the first retry; it doubles on every
+// further failure up to maxBackoff. A variable so tests can shorten it.
+var firstBackoff = 30 * time.Second
+
+// Entry is one queued notification.
+type Entry struct {
+	ID          string           `json:"id"` // also the message ID, a UUID
+	ReceiverID  string           `json:"receiverId"`
+	Message     notifier.Message `json:"message"`
+	Created     time.Time        `json:"created"`
+	Attempts    int              `json:"attempts"`
+	NextAttempt time.Time        `json:"nextAttempt"`         // zero once failed
+	Deferred    bool             `json:"deferred,omitempty"`  // held for quiet hours
+	LastError   string           `json:"lastError,omitempty"` // from the latest attempt
+	Failed      bool             `json:"failed,omitempty"`    // gave up; re-send with Retry
+	FailedAt    time.Time        `json:"failedAt"`            // when it gave up
+}
+
+// ErrNotFound is returned for an unknown entry ID.
+var ErrNotFound = errors.New("no such notification")
+
+var (
+	mu      sync.Mutex
+	loaded  bool
+	entries []*Entry            // oldest first
+	sending = map[string]bool{} // entry IDs with an attempt in flight
+	wake    = make(chan struct{}, 1)
+	paused  = func() bool { return false }
+)
+
+// SetPauseCheck sets how the outbox learns that notifications are paused.
+// Deferred notifications that come due while paused are dropped.
+func SetPauseCheck(f func() bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	paused = f
+}
+
+// loadLocked reads the outbox file on first use. Callers must hold mu.
+func loadLocked() {
+	if loaded {
+		return
+	}
+	loaded = true
+	var stored []*Entry
+	if _, err := statefile.Load(outboxPath, &stored); err != nil {
+		log.Printf("Error loading outbox, starting empty: %v", err)
+		return
+	}
+	entries = stored
+}
+
+func saveLocked() {
+	if err := statefile.Save(outboxPath, entries); err != nil {
+		log.Printf("Error saving outbox: %v", err)
+	}
+}
+
+// signal wakes the worker without blocking.
+func signal() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue stores a notification for delivery and wakes the worker. The
+// message is given an ID if it has none.
+func Enqueue(receiverID string, msg notifier.Message) {
+	EnqueueAt(receiverID, msg, time.Now())
+}
+
+// EnqueueAt is Enqueue for a notification that is not sent before at, such
+// as a batch waiting for its window to close. It returns the entry's ID.
+func EnqueueAt(receiverID string, msg notifier.Message, at time.Time) string {
+	return add(receiverID, msg, at, false)
+}
+
+// Defer holds a notification back until a quiet-hours window ends. It is
+// dropped instead if notifications are paused by then.
+func Defer(receiverID string, msg notifier.Message, until time.Time) {
+	add(receiverID, msg, until, true)
+}
+
+func add(receiverID string, msg notifier.Message, at time.Time, deferred bool) string {
+	if msg.ID == "" {
+		msg.ID = newID()
+	}
+
+	mu.Lock()
+	loadLocked()
+	entries = append(entries, &Entry{ID: msg.ID, ReceiverID: receiverID, Message: msg, Created: time.Now(), NextAttempt: at, Deferred: deferred})
+	saveLocked()
+	mu.Unlock()
+	signal()
+	return msg.ID
+}
+
+// Update replaces the message of a notification that has not been attempted
+// yet, keeping its ID. It reports false once the notification is being sent,
+// has been sent or was discarded.
+func Update(id string, msg notifier.Message) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	loadLocked()
+	e := findLocked(id)
+	if e == nil || e.Attempts > 0 || sending[id] {
+		return false
+	}
+	msg.ID = e.ID
+	e.Message = msg
+	saveLocked()
+	return true
+}
+
+// Entries returns the queued and failed notifications, oldest first.
+func Entries() []Entry {
+	mu.Lock()
+	defer mu.Unlock()
+	loadLocked()
+	out := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, *e)
+	}
+	return out
+}
+
+// Retry queues a failed notification again. It keeps its ID, so a delivery
+// that did go through after all is not duplicated on LINE.
+func Retry(id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	loadLocked()
+	e := findLocked(id)
+	if e == nil {
+		return ErrNotFound
+	}
+	if !e.Failed {
+		return fmt.Errorf("notification %s has not failed", id)
+	}
+	e.Failed, e.FailedAt, e.Attempts, e.NextAttempt = false, time.Time{}, 0, time.Now()
+	saveLocked()
+	signal()
+	return nil
+}
+
+// Discard removes a notification that is not being sent right now.
+func Discard(id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	loadLocked()
+	if findLocked(id) == nil {
+		return ErrNotFound
+	}
+	if sending[id] {
+		return fmt.Errorf("notification %s is being sent", id)
+	}
+	removeLocked(id)
+	saveLocked()
+	return nil
+}
+
+func findLocked(id string) *Entry {
+	for _, e := range entries {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+func removeLocked(id string) {
+	entries = slices.DeleteFunc(entries, func(e *Entry) bool { return e.ID == id })
+}
+
+// Run delivers queued notifications until ctx is done, including those left
+// over from a previous run.
+func Run(ctx context.Context) {
+	for {
+		wait := dispatchDue(ctx, time.Now())
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// dispatchDue starts an attempt for every due notification and returns how
+// long to wait for the next one.
+func dispatchDue(ctx context.Context, now time.Time) time.Duration {
+	mu.Lock()
+	defer mu.Unlock()
+	loadLocked()
+
+	wait := time.Hour
+	dropped := false
+	for _, e := range slices.Clone(entries) {
+		if e.Failed || sending[e.ID] {
+			continue
+		}
+		if d := e.NextAttempt.Sub(now); d > 0 {
+			wait = min(wait, d)
+			continue
+		}
+		if e.Deferred && e.Attempts == 0 && paused() {
+			log.Printf("Paused: dropped deferred notification to %s.", e.ReceiverID)
+			removeLocked(e.ID)
+			dropped = true
+			continue
+		}
+		sending[e.ID] = true
+		go attempt(ctx, *e)
+	}
+	if dropped {
+		saveLocked()
+	}
+	return wait
+}
+
+// attempt sends one notification and records the outcome.
+func attempt(ctx context.Context, e Entry) {
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	err := notifier.Send(sendCtx, e.ReceiverID, e.Message)
+	cancel()
+	record(e.ID, err, time.Now())
+	signal()
+}
+
+// record applies the outcome of an attempt: a delivered notification leaves
+// the outbox, a failed one is retried with exponential backoff until it runs
+// out of attempts. Unconfigured channels and permanent errors are not retried.
+func record(id string, err error, now time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(sending, id)
+
+	e := findLocked(id)
+	if e == nil {
+		return // discarded meanwhile
+	}
+	if err == nil {
+		log.Printf("Notification sent to %s", e.ReceiverID)
+		removeLocked(id)
+		saveLocked()
+		return
+	}
+
+	e.Attempts++
+	e.LastError = err.Error()
+	if e.Attempts >= maxAttempts || errors.Is(err, notifier.ErrUnknownChannel) || errors.Is(err, notifier.ErrPermanent) {
+		log.Printf("Error sending notification to %s, giving up after %d attempt(s): %v", e.ReceiverID, e.Attempts, err)
+		e.Failed, e.FailedAt, e.NextAttempt = true, now, time.Time{}
+		pruneFailedLocked()
+	} else {
+		e.NextAttempt = now.Add(backoff(e.Attempts))
+		log.Printf("Error sending notification to %s (attempt %d), retrying at %s: %v", e.ReceiverID, e.Attempts, e.NextAttempt.Format(time.DateTime), err)
+	}
+	saveLocked()
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	d := firstBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// pruneFailedLocked drops the oldest failed notifications beyond maxFailed.
+func pruneFailedLocked() {
+	failed := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].Failed {
+			continue
+		}
+		if failed++; failed > maxFailed {
+			entries = slices.Delete(entries, i, i+1)
+		}
+	}
+}
+
+// newID returns a random (version 4) UUID, the format LINE requires for
+// X-Line-Retry-Key.
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
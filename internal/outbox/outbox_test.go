@@ -0,0 +1,240 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+// flaky fails the first f.failures sends, then records the messages it gets.
+type flaky struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     []notifier.Message
+}
+
+func (f *flaky) Send(_ context.Context, _ string, msg notifier.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.attempts <= f.failures {
+		return errors.New("unreachable")
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func (f *flaky) delivered() []notifier.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]notifier.Message(nil), f.sent...)
+}
+
+// resetOutbox starts each test from an empty outbox in a temp dir.
+func resetOutbox(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	mu.Lock()
+	defer mu.Unlock()
+	loaded, entries, sending = false, nil, map[string]bool{}
+	paused = func() bool { return false }
+}
+
+func register(t *testing.T, n notifier.Notifier) {
+	t.Helper()
+	notifier.Register(config.ChannelLine, n)
+	t.Cleanup(func() { notifier.Register(config.ChannelLine, unconfigured{}) })
+}
+
+// unconfigured is a channel without settings, which fails every send with
+// notifier.ErrUnknownChannel.
+type unconfigured struct{ notifier.Notifier }
+
+func (unconfigured) Configured() bool { return false }
+
+// runWorker runs the worker until the test ends.
+func runWorker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { Run(ctx); close(done) }()
+	t.Cleanup(func() { cancel(); <-done })
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestDeliverRetriesWithStableID(t *testing.T) {
+	resetOutbox(t)
+	defer func(d time.Duration) { firstBackoff = d }(firstBackoff)
+	firstBackoff = time.Millisecond
+
+	f := &flaky{failures: 2}
+	register(t, f)
+	runWorker(t)
+
+	Enqueue("U1", notifier.Message{Text: "Mom is home"})
+	waitFor(t, "delivery", func() bool { return len(f.delivered()) == 1 })
+	waitFor(t, "the outbox to empty", func() bool { return len(Entries()) == 0 })
+
+	msg := f.delivered()[0]
+	if msg.Text != "Mom is home" {
+		t.Errorf("delivered %q", msg.Text)
+	}
+	if !uuidRegex.MatchString(msg.ID) {
+		t.Errorf("message ID %q is not a UUID", msg.ID)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.attempts != 3 {
+		t.Errorf("attempts = %d, want 3", f.attempts)
+	}
+}
+
+func TestUnknownChannelFailsAndRetry(t *testing.T) {
+	resetOutbox(t)
+	register(t, unconfigured{})
+	runWorker(t)
+
+	Enqueue("U1", notifier.Message{Text: "hi"})
+	waitFor(t, "the notification to fail", func() bool {
+		e := Entries()
+		return len(e) == 1 && e[0].Failed
+	})
+	e := Entries()[0]
+	if e.Attempts != 1 || e.LastError == "" {
+		t.Errorf("failed entry = %+v, want 1 attempt and an error", e)
+	}
+
+	f := &flaky{}
+	register(t, f)
+	if err := Retry(e.ID); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	waitFor(t, "the re-sent notification", func() bool { return len(f.delivered()) == 1 })
+	if got := f.delivered()[0].ID; got != e.ID {
+		t.Errorf("re-sent with ID %q, want the original %q", got, e.ID)
+	}
+
+	if err := Retry(e.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Retry of a delivered notification = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	resetOutbox(t)
+	Enqueue("U1", notifier.Message{Text: "hi"})
+	id := Entries()[0].ID
+	if err := Discard(id); err != nil {
+		t.Fatalf("Discard: %v", err)
+	}
+	if n := len(Entries()); n != 0 {
+		t.Errorf("%d entries left after discarding", n)
+	}
+	if err := Discard(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Discard = %v, want ErrNotFound", err)
+	}
+}
+
+func TestQueueSurvivesRestart(t *testing.T) {
+	resetOutbox(t)
+	Enqueue("telegram:42", notifier.Message{Text: "hi", Kind: config.TriggerArrival, Subject: "Mom"})
+
+	// Forget the in-memory state as a restart would.
+	mu.Lock()
+	loaded, entries = false, nil
+	mu.Unlock()
+
+	e := Entries()
+	if len(e) != 1 || e[0].ReceiverID != "telegram:42" || e[0].Message.Subject != "Mom" || e[0].Message.ID != e[0].ID {
+		t.Errorf("reloaded outbox = %+v", e)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  firstBackoff,
+		2:  2 * firstBackoff,
+		3:  4 * firstBackoff,
+		20: maxBackoff,
+	}
+	for attempts, want := range cases {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestRecordGivesUpAfterMaxAttempts(t *testing.T) {
+	resetOutbox(t)
+	Enqueue("U1", notifier.Message{Text: "hi"})
+	id := Entries()[0].ID
+
+	now := time.Now()
+	for range maxAttempts {
+		record(id, errors.New("unreachable"), now)
+	}
+	e := Entries()[0]
+	if !e.Failed || e.Attempts != maxAttempts || !e.NextAttempt.IsZero() {
+		t.Errorf("entry after %d failures = %+v, want failed", maxAttempts, e)
+	}
+}
+
+func TestUpdateBeforeFirstAttempt(t *testing.T) {
+	resetOutbox(t)
+	id := EnqueueAt("U1", notifier.Message{Text: "Mom is home"}, time.Now().Add(time.Hour))
+	if !Update(id, notifier.Message{Text: "Mom and Dad are home"}) {
+		t.Fatal("Update of a waiting notification failed")
+	}
+	e := Entries()[0]
+	if e.Message.Text != "Mom and Dad are home" || e.Message.ID != id {
+		t.Errorf("updated entry = %+v", e)
+	}
+
+	record(id, errors.New("unreachable"), time.Now())
+	if Update(id, notifier.Message{Text: "too late"}) {
+		t.Error("Update succeeded after an attempt")
+	}
+}
+
+func TestDeferredDroppedWhilePaused(t *testing.T) {
+	resetOutbox(t)
+	SetPauseCheck(func() bool { return true })
+	now := time.Now()
+	Defer("U1", notifier.Message{Text: "quiet"}, now)
+	EnqueueAt("U2", notifier.Message{Text: "alert"}, now.Add(time.Hour))
+
+	dispatchDue(context.Background(), now)
+	e := Entries()
+	if len(e) != 1 || e[0].ReceiverID != "U2" {
+		t.Errorf("entries after dispatch = %+v, want only the pending U2", e)
+	}
+}
+
+func TestRecordGivesUpOnPermanentError(t *testing.T) {
+	resetOutbox(t)
+	Enqueue("webhook:presence", notifier.Message{Text: "hi"})
+	id := Entries()[0].ID
+
+	record(id, fmt.Errorf("webhook returned 400 (%w)", notifier.ErrPermanent), time.Now())
+	if e := Entries()[0]; !e.Failed || e.Attempts != 1 {
+		t.Errorf("entry after a permanent error = %+v, want failed", e)
+	}
+}
@@ -0,0 +1,10 @@
+// Package presence holds the presence snapshot the monitor hands to outputs
+// such as the MQTT publisher after each scan cycle.
+package presence
+
+// State is a target's presence.
+type State struct {
+	Mac  string
+	Name string
+	Home bool
+}
@@ -0,0 +1,140 @@
+// Package rules matches presence events against the declarative rules of
+// targets.yaml and carries out the webhook and command actions.
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+	commandTimeout = 30 * time.Second
+)
+
+// Event is something that happened, as seen by the rules. Action templates
+// are rendered with its Data, like any other message: "{{.Name}} is home".
+type Event struct {
+	Trigger string    `json:"trigger"`
+	Subject string    `json:"subject,omitempty"` // target or person name
+	Mac     string    `json:"mac,omitempty"`
+	IP      string    `json:"ip,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Presence reports whether a target (by name or MAC) or a person (by name) is
+// home. known is false for names it doesn't track.
+type Presence func(name string) (home, known bool)
+
+// Match reports whether the rule runs for the event, and if not, why.
+func Match(r config.Rule, ev Event, present Presence) (bool, string) {
+	if !r.Enabled {
+		return false, "rule is disabled"
+	}
+	if r.Trigger != ev.Trigger {
+		return false, "trigger is " + r.Trigger
+	}
+	if len(r.Subjects) > 0 && !containsFold(r.Subjects, ev.Subject) && !containsFold(r.Subjects, ev.Mac) {
+		return false, "subject is not one of " + strings.Join(r.Subjects, ", ")
+	}
+
+	when := r.When
+	if len(when.Windows) > 0 {
+		if active, _ := when.Schedule().ActiveAt(ev.Time); !active {
+			return false, "outside the time windows"
+		}
+	}
+	if len(when.Days) > 0 {
+		day := strings.ToLower(ev.Time.In(when.Schedule().Location()).Weekday().String()[:3])
+		if !containsFold(when.Days, day) {
+			return false, "not on " + strings.Join(when.Days, ", ")
+		}
+	}
+	for _, n := range when.Present {
+		if home, _ := present(n); !home {
+			return false, n + " is not home"
+		}
+	}
+	for _, n := range when.Absent {
+		if home, _ := present(n); home {
+			return false, n + " is home"
+		}
+	}
+	return true, ""
+}
+
+func containsFold(list []string, s string) bool {
+	return s != "" && slices.ContainsFunc(list, func(v string) bool { return strings.EqualFold(v, s) })
+}
+
+// Data returns the event as message template data.
+func (ev Event) Data() config.MessageData {
+	return config.MessageData{Name: ev.Subject, Event: ev.Trigger, Time: ev.Time, Mac: ev.Mac, IP: ev.IP}
+}
+
+// Render fills a template with the event. Templates were checked by config
+// validation, so a failure only falls back to the raw text.
+func Render(text string, ev Event) string {
+	return config.RenderMessage(text, ev.Data())
+}
+
+// Webhook POSTs the event, with the rule name, as JSON to the action's URL.
+func Webhook(ctx context.Context, rule string, a config.RuleAction, ev Event) error {
+	body, err := json.Marshal(struct {
+		Rule string `json:"rule"`
+		Event
+	}{rule, ev})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned %s", a.URL, resp.Status)
+	}
+	return nil
+}
+
+// Command runs the action's program directly (no shell), with the event in
+// ARP_NOTIFY_* environment variables. Its arguments are run as given, so
+// callers render them first.
+func Command(ctx context.Context, rule string, a config.RuleAction, ev Event) error {
+	args := a.Command
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(),
+		"ARP_NOTIFY_RULE="+rule,
+		"ARP_NOTIFY_TRIGGER="+ev.Trigger,
+		"ARP_NOTIFY_SUBJECT="+ev.Subject,
+		"ARP_NOTIFY_MAC="+ev.Mac,
+		"ARP_NOTIFY_IP="+ev.IP,
+		"ARP_NOTIFY_TIME="+ev.Time.Format(time.RFC3339),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+func homeOnly(names ...string) Presence {
+	return func(name string) (bool, bool) {
+		for _, n := range names {
+			if n == name {
+				return true, true
+			}
+		}
+		return false, true
+	}
+}
+
+func TestMatch(t *testing.T) {
+	// 2026-03-06 is a Friday.
+	evening := time.Date(2026, 3, 6, 19, 30, 0, 0, time.UTC)
+	ev := Event{Trigger: config.TriggerArrival, Subject: "Amy", Mac: "aa:bb:cc:dd:ee:ff", Time: evening}
+	base := config.Rule{
+		Name:     "porch",
+		Enabled:  true,
+		Trigger:  config.TriggerArrival,
+		Subjects: []string{"amy"},
+		When: config.RuleConditions{
+			Timezone: "UTC",
+			Windows:  []config.TimeWindow{{Start: "18:00", End: "06:00"}},
+			Days:     []string{"fri"},
+			Present:  []string{"Mom"},
+			Absent:   []string{"Dad"},
+		},
+	}
+
+	if ok, reason := Match(base, ev, homeOnly("Mom")); !ok {
+		t.Fatalf("rule should match, got %q", reason)
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*config.Rule, *Event)
+		present Presence
+	}{
+		{"disabled", func(r *config.Rule, _ *Event) { r.Enabled = false }, homeOnly("Mom")},
+		{"other trigger", func(_ *config.Rule, e *Event) { e.Trigger = config.TriggerDeparture }, homeOnly("Mom")},
+		{"other subject", func(_ *config.Rule, e *Event) { e.Subject, e.Mac = "Bob", "" }, homeOnly("Mom")},
+		{"outside window", func(_ *config.Rule, e *Event) { e.Time = evening.Add(-6 * time.Hour) }, homeOnly("Mom")},
+		{"other day", func(r *config.Rule, _ *Event) { r.When.Days = []string{"sat"} }, homeOnly("Mom")},
+		{"required person away", nil, homeOnly()},
+		{"excluded person home", nil, homeOnly("Mom", "Dad")},
+	}
+	for _, tt := range tests {
+		r, e := base, ev
+		if tt.mutate != nil {
+			tt.mutate(&r, &e)
+		}
+		if ok, reason := Match(r, e, tt.present); ok || reason == "" {
+			t.Errorf("%s: Match = %v, %q; want no match with a reason", tt.name, ok, reason)
+		}
+	}
+
+	// Subjects also match by MAC, case-insensitively.
+	byMac := base
+	byMac.Subjects = []string{"AA:BB:CC:DD:EE:FF"}
+	if ok, reason := Match(byMac, ev, homeOnly("Mom")); !ok {
+		t.Errorf("subject by MAC should match, got %q", reason)
+	}
+}
+
+func TestRender(t *testing.T) {
+	ev := Event{Trigger: config.TriggerArrival, Subject: "Amy", Mac: "aa:bb:cc:dd:ee:ff"}
+	if got := Render("{{.Name}} ({{.Mac}}): {{.Event}}", ev); got != "Amy (aa:bb:cc:dd:ee:ff): arrival" {
+		t.Errorf("Render = %q", got)
+	}
+	if got := Render("{{.Nope}}", ev); got != "{{.Nope}}" {
+		t.Errorf("failed render should return the raw text, got %q", got)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var got struct {
+		Rule    string `json:"rule"`
+		Subject string `json:"subject"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	a := config.RuleAction{Type: config.ActionWebhook, URL: srv.URL}
+	if err := Webhook(context.Background(), "porch", a, Event{Subject: "Amy"}); err != nil {
+		t.Fatalf("Webhook: %v", err)
+	}
+	if got.Rule != "porch" || got.Subject != "Amy" {
+		t.Errorf("webhook payload = %+v", got)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	a := config.RuleAction{Type: config.ActionCommand, Command: []string{"sh", "-c", `test "$ARP_NOTIFY_SUBJECT" = Amy`}}
+	if err := Command(context.Background(), "porch", a, Event{Subject: "Amy"}); err != nil {
+		t.Errorf("Command: %v", err)
+	}
+	if err := Command(context.Background(), "porch", a, Event{Subject: "Bob"}); err == nil {
+		t.Error("a failing command should return an error")
+	}
+}
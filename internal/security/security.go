@@ -0,0 +1,217 @@
+// Package security watches the IP-to-MAC bindings reported by arp-scan for
+// signs of ARP spoofing and IP address conflicts.
+package security
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/arpscan"
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/statefile"
+)
+
+const (
+	securityPath = "security.json"
+
+	// realertAfter is how long an ongoing conflict stays quiet before it is
+	// announced again.
+	realertAfter = 24 * time.Hour
+	maxConflicts = 100
+)
+
+// Conflict kinds.
+const (
+	KindDuplicateIP    = "duplicate_ip"    // one IP answered from several MACs
+	KindGatewayChanged = "gateway_changed" // the gateway IP moved to another MAC
+	KindManyIPs        = "many_ips"        // one MAC answered for many IPs
+)
+
+// Conflict is a suspicious binding, kept for the web UI and updated while it
+// keeps showing up.
+type Conflict struct {
+	Kind        string    `json:"kind"`
+	IP          string    `json:"ip,omitempty"`
+	Macs        []string  `json:"macs"`
+	IPs         []string  `json:"ips,omitempty"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	LastAlerted time.Time `json:"lastAlerted"`
+}
+
+// key identifies the same conflict across scans.
+func (c Conflict) key() string {
+	return c.Kind + "|" + c.IP + "|" + strings.Join(c.Macs, ",")
+}
+
+// Message describes the conflict for a notification.
+func (c Conflict) Message() string {
+	switch c.Kind {
+	case KindDuplicateIP:
+		return fmt.Sprintf("IP conflict: %s answered from %d MACs (%s). Possible ARP spoofing.",
+			c.IP, len(c.Macs), strings.Join(c.Macs, ", "))
+	case KindGatewayChanged:
+		return fmt.Sprintf("Gateway %s changed MAC from %s to %s. Possible ARP spoofing.",
+			c.IP, c.Macs[0], c.Macs[1])
+	case KindManyIPs:
+		return fmt.Sprintf("MAC %s answered for %d IPs (%s). Possible ARP spoofing.",
+			c.Macs[0], len(c.IPs), strings.Join(c.IPs, ", "))
+	}
+	return "Suspicious ARP activity: " + c.Kind
+}
+
+// Binding is the MAC an IP last answered from.
+type Binding struct {
+	Mac       string    `json:"mac"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+type stateFile struct {
+	Bindings  map[string]Binding `json:"bindings"` // IP -> binding
+	Conflicts []Conflict         `json:"conflicts"`
+}
+
+var (
+	mu     sync.Mutex
+	loaded bool
+	st     stateFile
+)
+
+func loadLocked() {
+	if loaded {
+		return
+	}
+	loaded = true
+	st = stateFile{Bindings: make(map[string]Binding)}
+	if _, err := statefile.Load(securityPath, &st); err != nil {
+		log.Printf("Error loading security state, starting empty: %v", err)
+	}
+	if st.Bindings == nil {
+		st.Bindings = make(map[string]Binding)
+	}
+}
+
+// Analyze inspects one broadcast scan, updates the tracked bindings and returns
+// the conflicts that should be announced now: new ones, and ongoing ones last
+// announced more than a day ago.
+func Analyze(hosts []arpscan.Host, cfg config.Security, now time.Time) []Conflict {
+	if !cfg.Enabled || len(hosts) == 0 {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	loadLocked()
+
+	var found []Conflict
+
+	macsByIP := make(map[string][]string)
+	ipsByMac := make(map[string][]string)
+	for _, h := range hosts {
+		if !slices.Contains(macsByIP[h.IP], h.MAC) {
+			macsByIP[h.IP] = append(macsByIP[h.IP], h.MAC)
+		}
+		if !slices.Contains(ipsByMac[h.MAC], h.IP) {
+			ipsByMac[h.MAC] = append(ipsByMac[h.MAC], h.IP)
+		}
+	}
+
+	for ip, macs := range macsByIP {
+		if len(macs) > 1 {
+			sort.Strings(macs)
+			found = append(found, Conflict{Kind: KindDuplicateIP, IP: ip, Macs: macs})
+		}
+	}
+	for mac, ips := range ipsByMac {
+		if len(ips) > cfg.IPLimit() {
+			sort.Strings(ips)
+			found = append(found, Conflict{Kind: KindManyIPs, Macs: []string{mac}, IPs: ips})
+		}
+	}
+
+	// Gateway: compare against the binding from earlier scans before updating it.
+	if gw := cfg.GatewayIP; gw != "" {
+		if macs := macsByIP[gw]; len(macs) == 1 {
+			if prev, ok := st.Bindings[gw]; ok && prev.Mac != macs[0] {
+				found = append(found, Conflict{Kind: KindGatewayChanged, IP: gw, Macs: []string{prev.Mac, macs[0]}})
+			}
+		}
+	}
+
+	for ip, macs := range macsByIP {
+		if len(macs) != 1 {
+			continue // ambiguous; keep the previous binding
+		}
+		b := st.Bindings[ip]
+		if b.Mac != macs[0] {
+			b = Binding{Mac: macs[0], FirstSeen: now}
+		}
+		b.LastSeen = now
+		st.Bindings[ip] = b
+	}
+
+	var alerts []Conflict
+	for _, c := range found {
+		if recordLocked(c, now) {
+			alerts = append(alerts, c)
+		}
+	}
+
+	if err := statefile.Save(securityPath, st); err != nil {
+		log.Printf("Error saving security state: %v", err)
+	}
+	return alerts
+}
+
+// recordLocked merges a conflict into the history and reports whether it
+// should be announced.
+func recordLocked(c Conflict, now time.Time) bool {
+	for i := range st.Conflicts {
+		existing := &st.Conflicts[i]
+		if existing.key() != c.key() {
+			continue
+		}
+		existing.LastSeen = now
+		existing.IPs = c.IPs
+		if now.Sub(existing.LastAlerted) < realertAfter {
+			return false
+		}
+		existing.LastAlerted = now
+		return true
+	}
+
+	c.FirstSeen, c.LastSeen, c.LastAlerted = now, now, now
+	st.Conflicts = append([]Conflict{c}, st.Conflicts...)
+	if len(st.Conflicts) > maxConflicts {
+		st.Conflicts = st.Conflicts[:maxConflicts]
+	}
+	return true
+}
+
+// Conflicts returns the recorded conflicts, most recently seen first.
+func Conflicts() []Conflict {
+	mu.Lock()
+	defer mu.Unlock()
+	loadLocked()
+
+	out := slices.Clone(st.Conflicts)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].LastSeen.After(out[j].LastSeen) })
+	return out
+}
+
+// ClearConflicts forgets all recorded conflicts, e.g. once they were reviewed.
+// IP bindings are kept so gateway changes are still detected.
+func ClearConflicts() error {
+	mu.Lock()
+	defer mu.Unlock()
+	loadLocked()
+
+	st.Conflicts = nil
+	return statefile.Save(securityPath, st)
+}
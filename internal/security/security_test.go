@@ -0,0 +1,113 @@
+package security
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/arpscan"
+	"github.com/nekogravitycat/arp-notify/internal/config"
+)
+
+func resetSecurity(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	mu.Lock()
+	defer mu.Unlock()
+	loaded = false
+	st = stateFile{}
+}
+
+var enabled = config.Security{Enabled: true, GatewayIP: "192.168.0.1"}
+
+func TestAnalyzeDuplicateIP(t *testing.T) {
+	resetSecurity(t)
+
+	hosts := []arpscan.Host{
+		{IP: "192.168.0.5", MAC: "aa:bb:cc:dd:ee:05"},
+		{IP: "192.168.0.5", MAC: "aa:bb:cc:dd:ee:06"},
+	}
+	now := time.Now()
+	alerts := Analyze(hosts, enabled, now)
+	if len(alerts) != 1 || alerts[0].Kind != KindDuplicateIP || len(alerts[0].Macs) != 2 {
+		t.Fatalf("expected one duplicate_ip alert, got %+v", alerts)
+	}
+
+	if again := Analyze(hosts, enabled, now.Add(time.Hour)); len(again) != 0 {
+		t.Errorf("an ongoing conflict should not re-alert within a day, got %+v", again)
+	}
+	if again := Analyze(hosts, enabled, now.Add(25*time.Hour)); len(again) != 1 {
+		t.Errorf("an ongoing conflict should re-alert after a day, got %+v", again)
+	}
+	if n := len(Conflicts()); n != 1 {
+		t.Errorf("recorded %d conflicts, want 1", n)
+	}
+}
+
+func TestAnalyzeGatewayChanged(t *testing.T) {
+	resetSecurity(t)
+
+	now := time.Now()
+	if alerts := Analyze([]arpscan.Host{{IP: "192.168.0.1", MAC: "aa:bb:cc:dd:ee:01"}}, enabled, now); len(alerts) != 0 {
+		t.Fatalf("the first sighting of the gateway only records it, got %+v", alerts)
+	}
+
+	alerts := Analyze([]arpscan.Host{{IP: "192.168.0.1", MAC: "aa:bb:cc:dd:ee:66"}}, enabled, now.Add(time.Minute))
+	if len(alerts) != 1 || alerts[0].Kind != KindGatewayChanged {
+		t.Fatalf("expected a gateway_changed alert, got %+v", alerts)
+	}
+	if alerts[0].Macs[0] != "aa:bb:cc:dd:ee:01" || alerts[0].Macs[1] != "aa:bb:cc:dd:ee:66" {
+		t.Errorf("macs = %v, want old then new", alerts[0].Macs)
+	}
+}
+
+func TestAnalyzeManyIPs(t *testing.T) {
+	resetSecurity(t)
+
+	cfg := enabled
+	cfg.MaxIPsPerMac = 3
+	hosts := []arpscan.Host{
+		{IP: "192.168.0.10", MAC: "aa:bb:cc:dd:ee:99"},
+		{IP: "192.168.0.11", MAC: "aa:bb:cc:dd:ee:99"},
+	}
+	if alerts := Analyze(hosts, cfg, time.Now()); len(alerts) != 0 {
+		t.Fatalf("below the limit, got %+v", alerts)
+	}
+
+	hosts = append(hosts, arpscan.Host{IP: "192.168.0.12", MAC: "aa:bb:cc:dd:ee:99"})
+	if alerts := Analyze(hosts, cfg, time.Now()); len(alerts) != 0 {
+		t.Fatalf("at the limit, got %+v", alerts)
+	}
+
+	hosts = append(hosts, arpscan.Host{IP: "192.168.0.13", MAC: "aa:bb:cc:dd:ee:99"})
+	alerts := Analyze(hosts, cfg, time.Now())
+	if len(alerts) != 1 || alerts[0].Kind != KindManyIPs || len(alerts[0].IPs) != 4 {
+		t.Fatalf("expected a many_ips alert, got %+v", alerts)
+	}
+}
+
+func TestAnalyzeDisabled(t *testing.T) {
+	resetSecurity(t)
+
+	hosts := []arpscan.Host{
+		{IP: "192.168.0.5", MAC: "aa:bb:cc:dd:ee:05"},
+		{IP: "192.168.0.5", MAC: "aa:bb:cc:dd:ee:06"},
+	}
+	if alerts := Analyze(hosts, config.Security{}, time.Now()); len(alerts) != 0 {
+		t.Errorf("disabled monitor should not alert, got %+v", alerts)
+	}
+}
+
+func TestSecurityStatePersists(t *testing.T) {
+	resetSecurity(t)
+	Analyze([]arpscan.Host{{IP: "192.168.0.1", MAC: "aa:bb:cc:dd:ee:01"}}, enabled, time.Now())
+
+	mu.Lock()
+	loaded = false
+	st = stateFile{}
+	mu.Unlock()
+
+	alerts := Analyze([]arpscan.Host{{IP: "192.168.0.1", MAC: "aa:bb:cc:dd:ee:02"}}, enabled, time.Now())
+	if len(alerts) != 1 || alerts[0].Kind != KindGatewayChanged {
+		t.Errorf("gateway binding should survive a restart, got %+v", alerts)
+	}
+}
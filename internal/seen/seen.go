@@ -0,0 +1,80 @@
+// Package seen remembers the chats that recently messaged a bot, for the
+// receiver picker in the web UI, so users don't have to copy IDs by hand.
+package seen
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Chat is a user or group chat that has recently messaged a bot.
+type Chat struct {
+	ID       string    `json:"id"` // receiver ID
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind,omitempty"` // channel-specific, e.g. LINE's user, group or room
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// Store holds up to a fixed number of chats, dropping the least-recently-seen
+// one when full. It is safe for concurrent use.
+type Store struct {
+	max int
+
+	mu    sync.Mutex
+	chats map[string]Chat
+}
+
+// NewStore returns an empty store holding at most max chats.
+func NewStore(max int) *Store {
+	return &Store{max: max, chats: make(map[string]Chat)}
+}
+
+// Record upserts a chat, updating its last-seen time and (if provided) its
+// display name.
+func (s *Store) Record(id, kind, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.chats[id]
+	c.ID = id
+	c.Kind = kind
+	if name != "" {
+		c.Name = name
+	}
+	c.LastSeen = time.Now()
+	s.chats[id] = c
+
+	if len(s.chats) > s.max {
+		var oldestID string
+		var oldest time.Time
+		first := true
+		for k, v := range s.chats {
+			if first || v.LastSeen.Before(oldest) {
+				oldest, oldestID, first = v.LastSeen, k, false
+			}
+		}
+		delete(s.chats, oldestID)
+	}
+}
+
+// NeedsName reports whether the store still lacks a display name for the chat.
+func (s *Store) NeedsName(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.chats[id]
+	return !ok || c.Name == ""
+}
+
+// List returns the chats, most recent first.
+func (s *Store) List() []Chat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Chat, 0, len(s.chats))
+	for _, c := range s.chats {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeen.After(out[j].LastSeen) })
+	return out
+}
@@ -0,0 +1,90 @@
+package seen
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRecordUpsert(t *testing.T) {
+	s := NewStore(50)
+
+	s.Record("U1", "user", "")
+	if !s.NeedsName("U1") {
+		t.Error("chat recorded without a name should still need a name")
+	}
+
+	s.Record("U1", "user", "Alice")
+	if s.NeedsName("U1") {
+		t.Error("chat should no longer need a name after recording one")
+	}
+
+	// An empty name must not wipe an existing name.
+	s.Record("U1", "user", "")
+	if s.NeedsName("U1") {
+		t.Error("empty name should not clear an existing name")
+	}
+
+	chats := s.List()
+	if len(chats) != 1 {
+		t.Fatalf("expected 1 chat, got %d", len(chats))
+	}
+	if chats[0].Name != "Alice" || chats[0].Kind != "user" {
+		t.Errorf("chat = %+v, want Alice (user)", chats[0])
+	}
+}
+
+func TestNeedsNameUnknown(t *testing.T) {
+	if !NewStore(50).NeedsName("nope") {
+		t.Error("unknown chat should need a name")
+	}
+}
+
+func TestRecordEvictsOldest(t *testing.T) {
+	const max = 50
+	s := NewStore(max)
+
+	// Seed the store to its cap with controlled, strictly-increasing last-seen
+	// times so "oldest" is unambiguous (real time.Now() can tie on coarse clocks).
+	base := time.Now().Add(-time.Hour)
+	for i := range max {
+		id := fmt.Sprintf("U%03d", i)
+		s.chats[id] = Chat{ID: id, LastSeen: base.Add(time.Duration(i) * time.Minute)}
+	}
+
+	// Recording a new chat pushes past the cap and must evict the oldest (U000).
+	s.Record("Unew", "", "")
+
+	_, oldestStillThere := s.chats["U000"]
+	_, newPresent := s.chats["Unew"]
+	if n := len(s.chats); n != max {
+		t.Errorf("store size = %d, want %d", n, max)
+	}
+	if oldestStillThere {
+		t.Error("the oldest chat (U000) should have been evicted")
+	}
+	if !newPresent {
+		t.Error("the newly recorded chat should be present")
+	}
+}
+
+func TestListOrderedMostRecentFirst(t *testing.T) {
+	s := NewStore(50)
+
+	// Seed distinct last-seen times directly so ordering is unambiguous.
+	now := time.Now()
+	s.chats["U3"] = Chat{ID: "U3", LastSeen: now.Add(-3 * time.Minute)}
+	s.chats["U1"] = Chat{ID: "U1", LastSeen: now}
+	s.chats["U2"] = Chat{ID: "U2", LastSeen: now.Add(-1 * time.Minute)}
+
+	chats := s.List()
+	want := []string{"U1", "U2", "U3"}
+	if len(chats) != len(want) {
+		t.Fatalf("expected %d chats, got %d", len(want), len(chats))
+	}
+	for i, id := range want {
+		if chats[i].ID != id {
+			t.Errorf("position %d = %q, want %q", i, chats[i].ID, id)
+		}
+	}
+}
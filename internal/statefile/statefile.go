@@ -0,0 +1,62 @@
+// Package statefile persists runtime state (as opposed to user configuration)
+// as JSON files in the working directory.
+package statefile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Load decodes the JSON file at path into v. A missing file is not an error
+// and leaves v untouched; it reports whether the file existed.
+func Load(path string, v any) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return true, fmt.Errorf("failed to parse %q: %w", path, err)
+	}
+	return true, nil
+}
+
+// Save encodes v as indented JSON and atomically replaces the file at path.
+func Save(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %q: %w", path, err)
+	}
+	return WriteAtomic(path, data)
+}
+
+// WriteAtomic writes data to a temp file in the same directory and renames it
+// over the destination, so readers never observe a half-written file.
+func WriteAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, ".tmp-*"+filepath.Ext(path))
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to replace %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package statefile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMissingFile(t *testing.T) {
+	v := map[string]int{"keep": 1}
+	existed, err := Load(filepath.Join(t.TempDir(), "missing.json"), &v)
+	if err != nil || existed {
+		t.Fatalf("Load(missing) = %v, %v; want false, nil", existed, err)
+	}
+	if v["keep"] != 1 {
+		t.Error("a missing file should leave the value untouched")
+	}
+}
+
+func TestSaveAndLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := Save(path, map[string]int{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	var got map[string]int
+	existed, err := Load(path, &got)
+	if err != nil || !existed {
+		t.Fatalf("Load = %v, %v; want true, nil", existed, err)
+	}
+	if got["a"] != 1 || got["b"] != 2 {
+		t.Errorf("round trip = %v", got)
+	}
+
+	// No temp files are left behind.
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected only the state file, found %d entries", len(entries))
+	}
+}
+
+func TestLoadCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]int
+	if _, err := Load(path, &v); err == nil {
+		t.Error("expected an error for a corrupt file")
+	}
+}
@@ -0,0 +1,12 @@
+package telegram
+
+import "github.com/nekogravitycat/arp-notify/internal/seen"
+
+// seenChats holds the Telegram chats that recently messaged the bot, by
+// receiver ID ("telegram:<chat id>").
+var seenChats = seen.NewStore(50)
+
+// SeenChats returns recently-seen Telegram chats, most recent first.
+func SeenChats() []seen.Chat {
+	return seenChats.List()
+}
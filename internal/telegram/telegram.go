@@ -0,0 +1,217 @@
+// Package telegram is a Telegram bot: it pushes notifications to chats through
+// the Bot API and answers the same chat commands as the LINE bot, receiving
+// messages by long polling so no public webhook URL is needed.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+const (
+	pollTimeout  = 30 * time.Second // long-poll duration asked of getUpdates
+	pollBackoff  = 5 * time.Second  // wait after a failed poll
+	maxRetryWait = time.Minute
+)
+
+// CommandHandler answers a chat command from a chat, identified by its
+// receiver ID ("telegram:<chat id>"). It returns the reply and whether the
+// text was a command it handles.
+type CommandHandler func(receiverID, text string) (reply string, handled bool)
+
+// Bot talks to the Telegram Bot API.
+type Bot struct {
+	token    string
+	baseURL  string
+	client   *http.Client
+	commands CommandHandler
+}
+
+// New returns a bot for the token, as given by @BotFather.
+func New(token string) *Bot {
+	return &Bot{
+		token:   token,
+		baseURL: "https://api.telegram.org",
+		client:  &http.Client{Timeout: pollTimeout + 15*time.Second},
+	}
+}
+
+// SetCommandHandler installs the handler for chat commands other than
+// /whoami.
+func (b *Bot) SetCommandHandler(h CommandHandler) {
+	b.commands = h
+}
+
+// ReceiverID returns the receiver ID that addresses a chat.
+func ReceiverID(chatID int64) string {
+	return "telegram:" + strconv.FormatInt(chatID, 10)
+}
+
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// call invokes a Bot API method with a JSON body and decodes its result. A
+// 429 is retried once after the advertised retry_after.
+func (b *Bot) call(ctx context.Context, method string, params, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+"/bot"+b.token+"/"+method, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := b.client.Do(req)
+		if err != nil {
+			// The URL holds the token; keep it out of logs.
+			var uerr *url.Error
+			if errors.As(err, &uerr) {
+				return fmt.Errorf("telegram %s: %w", method, uerr.Err)
+			}
+			return fmt.Errorf("telegram %s: %w", method, err)
+		}
+		var r apiResponse
+		err = json.NewDecoder(resp.Body).Decode(&r)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("telegram %s: %s: %w", method, resp.Status, err)
+		}
+		if r.OK {
+			if result == nil {
+				return nil
+			}
+			return json.Unmarshal(r.Result, result)
+		}
+
+		wait := time.Duration(r.Parameters.RetryAfter) * time.Second
+		if resp.StatusCode != http.StatusTooManyRequests || attempt > 1 || wait > maxRetryWait {
+			return fmt.Errorf("telegram %s: %s", method, r.Description)
+		}
+		log.Printf("Telegram rate limited, retrying %s in %s.", method, wait)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Send pushes a text message to a chat ID (or @channel username), for the
+// notifier registry.
+func (b *Bot) Send(ctx context.Context, chatID string, msg notifier.Message) error {
+	return b.call(ctx, "sendMessage", map[string]any{"chat_id": chatID, "text": msg.Text}, nil)
+}
+
+type chat struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// name is how the chat shows up in the receiver picker.
+func (c chat) name() string {
+	if c.Title != "" {
+		return c.Title
+	}
+	if n := strings.TrimSpace(c.FirstName + " " + c.LastName); n != "" {
+		return n
+	}
+	return c.Username
+}
+
+type update struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Chat chat   `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+// Poll receives messages by long polling until ctx is done.
+func (b *Bot) Poll(ctx context.Context) {
+	var offset int64
+	for ctx.Err() == nil {
+		var updates []update
+		err := b.call(ctx, "getUpdates", map[string]any{
+			"offset":          offset,
+			"timeout":         int(pollTimeout / time.Second),
+			"allowed_updates": []string{"message"},
+		}, &updates)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Telegram polling failed: %v", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(pollBackoff):
+				}
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message != nil {
+				b.onMessage(ctx, u.Message.Chat, u.Message.Text)
+			}
+		}
+	}
+}
+
+// onMessage records the chat (for the web UI's receiver picker) and replies to
+// commands: /whoami with the chat's receiver ID, anything else through the
+// installed command handler.
+func (b *Bot) onMessage(ctx context.Context, c chat, text string) {
+	id := ReceiverID(c.ID)
+	seenChats.Record(id, "", c.name())
+
+	text = commandText(text)
+	if text == "" {
+		return
+	}
+	reply := id
+	if text != "whoami" {
+		if b.commands == nil {
+			return
+		}
+		var handled bool
+		if reply, handled = b.commands(id, text); !handled {
+			return
+		}
+	}
+	if err := b.call(ctx, "sendMessage", map[string]any{"chat_id": c.ID, "text": reply}, nil); err != nil {
+		log.Printf("Error replying to %s: %v", id, err)
+	}
+}
+
+// commandText strips the Telegram command syntax: "/pause@my_bot 3d" becomes
+// "pause 3d". Plain text is returned as is.
+func commandText(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return text
+	}
+	cmd, args, _ := strings.Cut(text[1:], " ")
+	cmd, _, _ = strings.Cut(cmd, "@")
+	return strings.TrimSpace(cmd + " " + args)
+}
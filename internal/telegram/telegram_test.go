@@ -0,0 +1,95 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+// fakeAPI stands in for the Bot API, recording sendMessage calls.
+type fakeAPI struct {
+	mu   sync.Mutex
+	sent []map[string]any
+}
+
+func (f *fakeAPI) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/botTOKEN/") {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var params map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&params)
+		if strings.HasSuffix(r.URL.Path, "/sendMessage") {
+			f.mu.Lock()
+			f.sent = append(f.sent, params)
+			f.mu.Unlock()
+		}
+		_, _ = w.Write([]byte(`{"ok": true, "result": {}}`))
+	}
+}
+
+func newTestBot(t *testing.T) (*Bot, *fakeAPI) {
+	api := &fakeAPI{}
+	srv := httptest.NewServer(api.handler(t))
+	t.Cleanup(srv.Close)
+	b := New("TOKEN")
+	b.baseURL = srv.URL
+	return b, api
+}
+
+func TestSend(t *testing.T) {
+	b, api := newTestBot(t)
+	if err := b.Send(context.Background(), "-1001", notifier.Message{Text: "Mom is home"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(api.sent) != 1 || api.sent[0]["chat_id"] != "-1001" || api.sent[0]["text"] != "Mom is home" {
+		t.Errorf("sent = %+v", api.sent)
+	}
+}
+
+func TestSendReportsAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"ok": false, "description": "Bad Request: chat not found"}`))
+	}))
+	defer srv.Close()
+	b := New("TOKEN")
+	b.baseURL = srv.URL
+
+	err := b.Send(context.Background(), "42", notifier.Message{Text: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("want the API description in the error, got %v", err)
+	}
+}
+
+func TestOnMessage(t *testing.T) {
+	b, api := newTestBot(t)
+	var gotID, gotText string
+	b.SetCommandHandler(func(id, text string) (string, bool) {
+		gotID, gotText = id, text
+		return "Notifications paused.", text == "pause 3d"
+	})
+
+	c := chat{ID: 42, Type: "private", FirstName: "Amy"}
+	b.onMessage(context.Background(), c, "/whoami")
+	b.onMessage(context.Background(), c, "/pause@arp_bot 3d")
+	b.onMessage(context.Background(), c, "hello")
+
+	if len(api.sent) != 2 || api.sent[0]["text"] != "telegram:42" || api.sent[1]["text"] != "Notifications paused." {
+		t.Errorf("replies = %+v", api.sent)
+	}
+	if gotID != "telegram:42" || gotText != "hello" {
+		t.Errorf("command handler got %q, %q", gotID, gotText)
+	}
+
+	chats := SeenChats()
+	if len(chats) == 0 || chats[0].ID != "telegram:42" || chats[0].Name != "Amy" {
+		t.Errorf("seen chats = %+v", chats)
+	}
+}
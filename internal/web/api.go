@@ -2,13 +2,24 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/inventory"
 	"github.com/nekogravitycat/arp-notify/internal/linebot"
 	"github.com/nekogravitycat/arp-notify/internal/monitor"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+	"github.com/nekogravitycat/arp-notify/internal/outbox"
+	"github.com/nekogravitycat/arp-notify/internal/rules"
+	"github.com/nekogravitycat/arp-notify/internal/security"
+	"github.com/nekogravitycat/arp-notify/internal/seen"
+	"github.com/nekogravitycat/arp-notify/internal/telegram"
+	"github.com/nekogravitycat/arp-notify/internal/webhook"
 )
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -21,7 +32,8 @@ func writeError(w http.ResponseWriter, status int, msg string) {
 	writeJSON(w, status, map[string]string{"error": msg})
 }
 
-// handleSystem reads (GET) or saves (PUT) the system config.
+// handleSystem reads (GET) or saves (PUT) the system config. A save keeps
+// rules.allow_commands as it is.
 func handleSystem(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -32,6 +44,9 @@ func handleSystem(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
 			return
 		}
+		// Whether rules may run commands is only set in config.yaml: the API
+		// has no authentication.
+		cfg.Rules = config.GetSystemConfig().Rules
 		if err := config.SaveSystemConfig(cfg); err != nil {
 			writeError(w, http.StatusBadRequest, err.Error())
 			return
@@ -87,14 +102,43 @@ func handleContacts(w http.ResponseWriter, r *http.Request) {
 }
 
 type statusRow struct {
-	Mac      string    `json:"mac"`
-	Name     string    `json:"name"`
-	LastSeen time.Time `json:"lastSeen"`
-	Notified bool      `json:"notified"`
+	Mac      string            `json:"mac"`
+	Name     string            `json:"name"`
+	LastSeen time.Time         `json:"lastSeen"`
+	Present  bool              `json:"present"`
+	Notified bool              `json:"notified"`
+	Quieted  bool              `json:"quieted"`
+	Override *monitor.Override `json:"override,omitempty"`
+}
+
+type overviewResponse struct {
+	Devices   []statusRow             `json:"devices"`
+	People    []monitor.PersonStatus  `json:"people"`
+	Household monitor.HouseholdStatus `json:"household"`
+	Pause     monitor.PauseStatus     `json:"pause"`
+	Watchdog  monitor.WatchdogStatus  `json:"watchdog"`
 }
 
 // handleStatus returns the live device states joined with target names.
 func handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, deviceRows())
+}
+
+// handleOverview returns everything the Status page shows: the device states
+// as in /api/status, the presence of each configured person and of the
+// household as a whole, whether notifications are paused and the scan
+// watchdog's view.
+func handleOverview(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, overviewResponse{
+		Devices:   deviceRows(),
+		People:    monitor.PeopleSnapshot(),
+		Household: monitor.HouseholdSnapshot(),
+		Pause:     monitor.CurrentPause(),
+		Watchdog:  monitor.WatchdogSnapshot(),
+	})
+}
+
+func deviceRows() []statusRow {
 	nameByMac := make(map[string]string)
 	for _, t := range config.GetTargetsConfig().Targets {
 		nameByMac[strings.ToLower(t.Mac)] = t.Name
@@ -107,30 +151,36 @@ func handleStatus(w http.ResponseWriter, r *http.Request) {
 			Mac:      s.Mac,
 			Name:     nameByMac[strings.ToLower(s.Mac)],
 			LastSeen: s.LastSeen,
+			Present:  s.Present,
 			Notified: s.Notified,
+			Quieted:  s.Quieted,
+			Override: s.Override,
 		})
 	}
-	writeJSON(w, http.StatusOK, rows)
+	return rows
 }
 
-// handleSeenUsers returns recently-seen LINE users, preferring the friendly
-// name from the contacts registry over the fetched profile name.
+// handleSeenUsers returns the LINE users and Telegram chats that recently
+// messaged the bots, most recent first, preferring the friendly name from the
+// contacts registry over the fetched profile name.
 func handleSeenUsers(w http.ResponseWriter, r *http.Request) {
 	nameByID := make(map[string]string)
 	for _, c := range config.GetTargetsConfig().Contacts {
 		nameByID[c.ID] = c.Name
 	}
 
-	users := linebot.SeenUsers()
+	users := append(linebot.SeenUsers(), telegram.SeenChats()...)
 	for i := range users {
 		if n := nameByID[users[i].ID]; n != "" {
 			users[i].Name = n
 		}
 	}
+	slices.SortFunc(users, func(a, b seen.Chat) int { return b.LastSeen.Compare(a.LastSeen) })
 	writeJSON(w, http.StatusOK, users)
 }
 
-// handleTestNotify sends a one-off LINE push so a receiver ID can be verified.
+// handleTestNotify sends a one-off message through the receiver's channel so
+// a receiver ID can be verified.
 func handleTestNotify(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -153,9 +203,444 @@ func handleTestNotify(w http.ResponseWriter, r *http.Request) {
 		req.Message = "arp-notify test notification"
 	}
 
-	if err := linebot.SendMessage(req.ID, req.Message); err != nil {
-		writeError(w, http.StatusBadGateway, "failed to send: "+err.Error())
+	msg := notifier.Message{Text: req.Message, Kind: "test", Subject: "arp-notify", Time: time.Now()}
+	if err := notifier.Send(r.Context(), req.ID, msg); err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, notifier.ErrUnknownChannel) {
+			status = http.StatusBadRequest
+		}
+		writeError(w, status, "failed to send: "+err.Error())
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "sent"})
 }
+
+type inventoryRow struct {
+	inventory.Device
+	Target bool   `json:"target"`
+	Name   string `json:"name"` // target name, when it is one
+}
+
+// handleDevices returns the LAN inventory, flagging hosts that are configured
+// targets.
+func handleDevices(w http.ResponseWriter, r *http.Request) {
+	targetByMac := make(map[string]config.Target)
+	for _, t := range config.GetTargetsConfig().Targets {
+		targetByMac[strings.ToLower(t.Mac)] = t
+	}
+
+	devices := inventory.Devices()
+	rows := make([]inventoryRow, 0, len(devices))
+	for _, d := range devices {
+		t, isTarget := targetByMac[d.Mac]
+		rows = append(rows, inventoryRow{Device: d, Target: isTarget, Name: t.Name})
+	}
+	writeJSON(w, http.StatusOK, rows)
+}
+
+// handleNewDevices lists the never-seen-before devices awaiting a decision.
+func handleNewDevices(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, monitor.PendingNewDevices())
+}
+
+type newDeviceRequest struct {
+	Mac  string `json:"mac"`
+	Name string `json:"name"`
+}
+
+func decodeNewDeviceRequest(w http.ResponseWriter, r *http.Request) (newDeviceRequest, bool) {
+	var req newDeviceRequest
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return req, false
+	}
+	if req.Mac == "" {
+		writeError(w, http.StatusBadRequest, "mac is required")
+		return req, false
+	}
+	return req, true
+}
+
+// handleNewDeviceKnown adds a new device to the allowlist and dismisses it.
+func handleNewDeviceKnown(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeNewDeviceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	cfg := config.GetTargetsConfig()
+	if !cfg.NewDevices.Allows(req.Mac) {
+		cfg.NewDevices.Allowlist = append(slices.Clone(cfg.NewDevices.Allowlist), config.KnownDevice{Mac: req.Mac, Name: req.Name})
+		if err := config.SaveTargetsConfig(cfg); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	_ = monitor.DismissNewDevice(req.Mac) // already allowlisted devices may not be pending
+	writeJSON(w, http.StatusOK, map[string]string{"status": "known"})
+}
+
+// handleNewDeviceTarget turns a new device into an enabled target, probing the
+// IP it was first seen at and falling back to the broadcast scan. Without a
+// name the target is named after the vendor, or else its MAC. A MAC that
+// already has a target is a conflict.
+func handleNewDeviceTarget(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeNewDeviceRequest(w, r)
+	if !ok {
+		return
+	}
+	cfg := config.GetTargetsConfig()
+	if slices.ContainsFunc(cfg.Targets, func(t config.Target) bool { return strings.EqualFold(t.Mac, req.Mac) }) {
+		writeError(w, http.StatusConflict, "a target with MAC "+req.Mac+" already exists")
+		return
+	}
+
+	target := config.Target{
+		Name:      strings.TrimSpace(req.Name),
+		Mac:       req.Mac,
+		Enabled:   true,
+		Detection: config.Detection{Mode: config.ModeBroadcast},
+		Receivers: []config.Receiver{},
+	}
+	for _, d := range monitor.PendingNewDevices() {
+		if strings.EqualFold(d.Mac, req.Mac) {
+			if target.Name == "" {
+				target.Name = d.Vendor
+			}
+			if d.IP != "" {
+				target.Detection = config.Detection{Mode: config.ModeAuto, IP: d.IP}
+			}
+		}
+	}
+	if target.Name == "" {
+		target.Name = req.Mac
+	}
+
+	cfg.Targets = append(slices.Clone(cfg.Targets), target)
+	if err := config.SaveTargetsConfig(cfg); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	_ = monitor.DismissNewDevice(req.Mac)
+	writeJSON(w, http.StatusOK, target)
+}
+
+// handleSecurity lists the recorded IP conflicts (GET) or clears them (DELETE).
+func handleSecurity(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, security.Conflicts())
+	case http.MethodDelete:
+		if err := security.ClearConflicts(); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "cleared"})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+type overrideRequest struct {
+	Mac      string    `json:"mac"`
+	Presence string    `json:"presence"`
+	Until    time.Time `json:"until"`
+}
+
+// decodeOverrideRequest parses an override request and resolves its MAC to the
+// spelling of the configured target, which device state is keyed by.
+func decodeOverrideRequest(w http.ResponseWriter, r *http.Request) (overrideRequest, bool) {
+	var req overrideRequest
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return req, false
+	}
+	mac, ok := targetMac(req.Mac)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no target with mac "+req.Mac)
+		return req, false
+	}
+	req.Mac = mac
+	return req, true
+}
+
+func targetMac(mac string) (string, bool) {
+	for _, t := range config.GetTargetsConfig().Targets {
+		if mac != "" && strings.EqualFold(t.Mac, mac) {
+			return t.Mac, true
+		}
+	}
+	return "", false
+}
+
+// handleOverrides lists the active overrides (GET) or clears those of one
+// target (DELETE ?mac=).
+func handleOverrides(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, monitor.Overrides())
+	case http.MethodDelete:
+		mac, ok := targetMac(r.URL.Query().Get("mac"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "no target with mac "+r.URL.Query().Get("mac"))
+			return
+		}
+		monitor.ClearOverride(mac)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "cleared"})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleOverridePresence forces a target present or absent, optionally until a
+// given time.
+func handleOverridePresence(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeOverrideRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := monitor.ForcePresence(req.Mac, req.Presence, req.Until); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleOverrideSnooze silences a target's notifications until a given time.
+func handleOverrideSnooze(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeOverrideRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := monitor.Snooze(req.Mac, req.Until); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+type pauseRequest struct {
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason"`
+}
+
+// handlePause reports (GET), starts (POST) or ends (DELETE) the global pause.
+func handlePause(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req pauseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		if err := monitor.Pause(req.Until, strings.TrimSpace(req.Reason)); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	case http.MethodDelete:
+		monitor.Resume()
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, monitor.CurrentPause())
+}
+
+// handleScan runs a scan cycle immediately. ?target= (name or MAC) probes a
+// single target. By default the per-target results are returned once the scan
+// is over; with ?stream=1 each result is written as a line of JSON as soon as
+// it is known.
+func handleScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(5 * time.Minute)) // a scan can outlast the server's write timeout
+
+	filter := r.URL.Query().Get("target")
+	stream := r.URL.Query().Get("stream") == "1"
+	start := time.Now()
+
+	var results []monitor.ScanResult
+	started := false
+	report := func(res monitor.ScanResult) {
+		if !stream {
+			results = append(results, res)
+			return
+		}
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		_ = json.NewEncoder(w).Encode(res)
+		_ = rc.Flush()
+	}
+
+	err := monitor.RunScan(filter, report)
+	switch {
+	case errors.Is(err, monitor.ErrScanInProgress):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusNotFound, err.Error())
+	case stream && !started:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+	case !stream:
+		if results == nil {
+			results = []monitor.ScanResult{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"results":    results,
+			"durationMs": time.Since(start).Milliseconds(),
+		})
+	}
+}
+
+// handleRulesDryRun evaluates the rules against a made-up event and the
+// current presence, returning what each rule would do without doing it.
+func handleRulesDryRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var ev rules.Event
+	if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	switch ev.Trigger {
+	case config.TriggerArrival, config.TriggerDeparture, config.TriggerHouseholdEmpty, config.TriggerHouseholdOccupied, config.TriggerUnknownDevice:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid trigger "+strconv.Quote(ev.Trigger))
+		return
+	}
+	writeJSON(w, http.StatusOK, monitor.DryRunRules(ev))
+}
+
+// handleMessagePreview renders a message template with an example event.
+// Fields left out of the request keep the example's values; receiver may be a
+// receiver ID, shown as its contact name.
+func handleMessagePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req struct {
+		Template   string     `json:"template"`
+		Name       string     `json:"name"`
+		Event      string     `json:"event"`
+		Time       *time.Time `json:"time"`
+		IP         string     `json:"ip"`
+		AwayForMin *int       `json:"away_for_min"`
+		Receiver   string     `json:"receiver"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+
+	data := config.SampleMessageData()
+	if req.Name != "" {
+		data.Name = req.Name
+	}
+	if req.Event != "" {
+		data.Event = req.Event
+	}
+	if req.Time != nil {
+		data.Time = *req.Time
+	}
+	if req.IP != "" {
+		data.IP = req.IP
+	}
+	if req.AwayForMin != nil {
+		data.AwayFor = time.Duration(*req.AwayForMin) * time.Minute
+	}
+	if req.Receiver != "" {
+		data.Receiver = config.GetTargetsConfig().ContactName(req.Receiver)
+	}
+
+	text, err := config.ExecuteMessage(req.Template, data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"text": text})
+}
+
+// handleStats returns time-at-home statistics computed from the persisted
+// presence history. ?subject= (target name or MAC, or person name) limits them
+// to one subject.
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, monitor.Stats(r.URL.Query().Get("subject"), time.Now()))
+}
+
+// handleChannels lists the delivery channels receiver IDs may name and those
+// configured for sending.
+func handleChannels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{
+		"known":      config.Channels(),
+		"configured": notifier.Channels(),
+	})
+}
+
+// handleWebhookDeliveries lists the recent outgoing webhook delivery attempts
+// with their status, most recent first.
+func handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, webhook.Deliveries())
+}
+
+// handleOutbox lists the queued and failed notifications (GET) or discards
+// one (DELETE ?id=).
+func handleOutbox(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, outbox.Entries())
+	case http.MethodDelete:
+		writeOutboxResult(w, outbox.Discard(r.URL.Query().Get("id")), "discarded")
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleOutboxRetry queues a failed notification (?id=) again.
+func handleOutboxRetry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeOutboxResult(w, outbox.Retry(r.URL.Query().Get("id")), "queued")
+}
+
+func writeOutboxResult(w http.ResponseWriter, err error, status string) {
+	switch {
+	case errors.Is(err, outbox.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"status": status})
+	}
+}
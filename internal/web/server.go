@@ -23,6 +23,24 @@ func RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/targets", handleTargets)
 	mux.HandleFunc("/api/contacts", handleContacts)
 	mux.HandleFunc("/api/status", handleStatus)
+	mux.HandleFunc("/api/overview", handleOverview)
 	mux.HandleFunc("/api/seen-users", handleSeenUsers)
 	mux.HandleFunc("/api/test-notify", handleTestNotify)
+	mux.HandleFunc("/api/channels", handleChannels)
+	mux.HandleFunc("/api/webhooks/deliveries", handleWebhookDeliveries)
+	mux.HandleFunc("/api/outbox", handleOutbox)
+	mux.HandleFunc("/api/outbox/retry", handleOutboxRetry)
+	mux.HandleFunc("/api/devices", handleDevices)
+	mux.HandleFunc("/api/new-devices", handleNewDevices)
+	mux.HandleFunc("/api/new-devices/known", handleNewDeviceKnown)
+	mux.HandleFunc("/api/new-devices/target", handleNewDeviceTarget)
+	mux.HandleFunc("/api/security", handleSecurity)
+	mux.HandleFunc("/api/overrides", handleOverrides)
+	mux.HandleFunc("/api/overrides/presence", handleOverridePresence)
+	mux.HandleFunc("/api/overrides/snooze", handleOverrideSnooze)
+	mux.HandleFunc("/api/pause", handlePause)
+	mux.HandleFunc("/api/scan", handleScan)
+	mux.HandleFunc("/api/rules/dry-run", handleRulesDryRun)
+	mux.HandleFunc("/api/messages/preview", handleMessagePreview)
+	mux.HandleFunc("/api/stats", handleStats)
 }
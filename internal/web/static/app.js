@@ -1,6 +1,6 @@
 "use strict";
 
-let currentTargets = { default_message: "", contacts: [], targets: [] };
+let currentTargets = { default_message: "", contacts: [], targets: [], people: [] };
 
 // ---------- helpers ----------
 
@@ -56,17 +56,33 @@ $all("nav.tabs button").forEach(btn => {
     btn.classList.add("active");
     $("#view-" + btn.dataset.tab).classList.add("active");
     if (btn.dataset.tab === "status") loadStatus();
+    if (btn.dataset.tab === "network") { loadNetwork(); loadConflicts(); }
   });
 });
 
+// Open the tab named in the URL fragment, e.g. /#status from a LINE card, or
+// /#status/new-device/<mac> from a new-device alert, which highlights its row.
+const [hashTab, hashKind, hashArg] = location.hash.slice(1).split("/");
+let focusNewDevice = hashKind === "new-device" ? decodeURIComponent(hashArg || "").toLowerCase() : "";
+{
+  const tab = $(`nav.tabs button[data-tab="${hashTab}"]`);
+  if (tab) tab.click();
+}
+
 // ---------- targets ----------
 
+// Delivery channels configured on the server, e.g. ["line"].
+let channels = [];
+api("GET", "/api/channels").then(c => { channels = c.configured || []; }).catch(() => {});
+
 function makeReceiver(card, receiver) {
   const node = $("#tpl-receiver").content.firstElementChild.cloneNode(true);
+  node.orig = receiver; // keeps fields the editor doesn't show (e.g. quiet_hours)
   $(".r-id", node).value = receiver.id || "";
   $(".r-message", node).value = receiver.message || "";
   $(".r-name", node).value = contactName(receiver.id);
   $(".rid", node).textContent = receiver.id || "";
+  if (channels.length) $(".r-id", node).title = "Configured channels: " + channels.join(", ");
 
   $(".r-id", node).addEventListener("input", e => {
     $(".rid", node).textContent = e.target.value.trim();
@@ -74,7 +90,7 @@ function makeReceiver(card, receiver) {
   $(".r-remove", node).addEventListener("click", () => node.remove());
   $(".r-test", node).addEventListener("click", async () => {
     const id = $(".r-id", node).value.trim();
-    if (!id) { toast("Enter a LINE ID first", "error"); return; }
+    if (!id) { toast("Enter a receiver ID first", "error"); return; }
     try {
       await api("POST", "/api/test-notify", { id, message: $(".r-message", node).value });
       toast("Test notification sent", "ok");
@@ -86,12 +102,25 @@ function makeReceiver(card, receiver) {
 
 function makeTarget(target) {
   const node = $("#tpl-target").content.firstElementChild.cloneNode(true);
+  node.orig = target;
   $(".t-enabled", node).checked = !!target.enabled;
   $(".t-name", node).value = target.name || "";
   $(".t-mac", node).value = target.mac || "";
   $(".t-mode", node).value = (target.detection && target.detection.mode) || "auto";
   $(".t-ip", node).value = (target.detection && target.detection.ip) || "";
   $(".t-message", node).value = target.message || "";
+  const policy = target.presence_policy || {};
+  $(".t-absence", node).value = policy.absence_reset_min || "";
+  $(".t-departure", node).value = policy.departure_delay_min || "";
+  $(".t-misses", node).value = policy.miss_threshold || "";
+  const quiet = target.quiet_hours || {};
+  $(".t-quiet-windows", node).value = formatWindows(quiet.windows);
+  $(".t-quiet-action", node).value = quiet.action || "suppress";
+  $(".t-quiet-tz", node).value = quiet.timezone || "";
+  const expect = target.expect || {};
+  $(".t-expect-windows", node).value = formatWindows(expect.windows);
+  $(".t-away-hours", node).value = expect.away_alert_hours || "";
+  $(".t-expect-tz", node).value = expect.timezone || "";
 
   const toggleIp = () => {
     const mode = $(".t-mode", node).value;
@@ -112,35 +141,164 @@ function renderTargets() {
   $("#targets-list").innerHTML = "";
   (currentTargets.targets || []).forEach(makeTarget);
   $("#default-message").value = currentTargets.default_message || "";
+  const batching = currentTargets.batching || {};
+  $("#batch-window").value = batching.window_sec || 0;
+  $("#batch-message").value = batching.message || "";
+  renderNewDevicesConfig();
+  renderSecurityConfig();
+  $("#admins .receivers").innerHTML = "";
+  (currentTargets.admins || []).forEach(r => makeReceiver($("#admins"), r));
+  const rules = currentTargets.rules || [];
+  $("#rules-count").textContent = rules.length + " rule(s), " + rules.filter(r => r.enabled).length + " enabled";
+  renderPeople();
+}
+
+function renderNewDevicesConfig() {
+  const nd = currentTargets.new_devices || {};
+  $("#nd-enabled").checked = !!nd.enabled;
+  $("#nd-message").value = nd.message || "";
+  $("#nd-allowlist").value = (nd.allowlist || [])
+    .map(k => k.mac + (k.name ? " " + k.name : "")).join("\n");
+  $("#new-devices .receivers").innerHTML = "";
+  (nd.receivers || []).forEach(r => makeReceiver($("#new-devices"), r));
+}
+
+function collectNewDevicesConfig(contactsMap) {
+  const allowlist = $("#nd-allowlist").value.split("\n").map(l => l.trim()).filter(Boolean).map(line => {
+    const i = line.search(/\s/);
+    return i < 0 ? { mac: line, name: "" } : { mac: line.slice(0, i), name: line.slice(i).trim() };
+  });
+  return {
+    ...currentTargets.new_devices, // keeps fields the editor doesn't show (e.g. quiet_hours)
+    enabled: $("#nd-enabled").checked,
+    message: $("#nd-message").value.trim(),
+    allowlist,
+    receivers: collectReceivers($("#new-devices"), contactsMap),
+  };
+}
+
+function renderSecurityConfig() {
+  const sec = currentTargets.security || {};
+  $("#sec-enabled").checked = !!sec.enabled;
+  $("#sec-gateway").value = sec.gateway_ip || "";
+  $("#sec-max-ips").value = sec.max_ips_per_mac || "";
+  $("#security .receivers").innerHTML = "";
+  (sec.receivers || []).forEach(r => makeReceiver($("#security"), r));
+}
+
+function collectSecurityConfig(contactsMap) {
+  return {
+    enabled: $("#sec-enabled").checked,
+    gateway_ip: $("#sec-gateway").value.trim(),
+    max_ips_per_mac: parseInt($("#sec-max-ips").value, 10) || 0,
+    receivers: collectReceivers($("#security"), contactsMap),
+  };
+}
+
+// Quiet-hours windows are edited one per line as "[days] HH:MM-HH:MM", e.g.
+// "mon,tue,wed,thu,fri 22:00-07:00" or just "23:00-06:00" for every day.
+function formatWindows(windows) {
+  return (windows || []).map(w =>
+    ((w.days && w.days.length) ? w.days.join(",") + " " : "") + w.start + "-" + w.end
+  ).join("\n");
+}
+
+function parseWindows(text) {
+  return text.split("\n").map(l => l.trim()).filter(Boolean).map(line => {
+    const parts = line.split(/\s+/);
+    const range = parts.pop();
+    const [start, end] = range.split("-");
+    const days = parts.length ? parts.join(",").split(",").filter(Boolean) : [];
+    return { days, start: start || "", end: end || "" };
+  });
+}
+
+function collectQuietHours(card) {
+  const windows = parseWindows($(".t-quiet-windows", card).value);
+  if (!windows.length) return {};
+  return {
+    timezone: $(".t-quiet-tz", card).value.trim(),
+    action: $(".t-quiet-action", card).value,
+    windows,
+  };
+}
+
+// collectExpect keeps the expectation fields the card doesn't edit (messages,
+// dedicated receivers).
+function collectExpect(card) {
+  return {
+    ...(card.orig.expect || {}),
+    timezone: $(".t-expect-tz", card).value.trim(),
+    windows: parseWindows($(".t-expect-windows", card).value),
+    away_alert_hours: +$(".t-away-hours", card).value || 0,
+  };
+}
+
+function collectReceivers(card, contactsMap) {
+  const receivers = [];
+  $all(".receiver", card).forEach(rc => {
+    const id = $(".r-id", rc).value.trim();
+    if (!id) return;
+    const name = $(".r-name", rc).value.trim();
+    if (name) contactsMap.set(id, name);
+    receivers.push({ ...rc.orig, id, message: $(".r-message", rc).value });
+  });
+  return receivers;
 }
 
 function collectTargets() {
   const contactsMap = new Map();
   (currentTargets.contacts || []).forEach(c => contactsMap.set(c.id, c.name));
 
-  const targets = $all("#targets-list .target").map(card => {
-    const receivers = [];
-    $all(".receiver", card).forEach(rc => {
-      const id = $(".r-id", rc).value.trim();
-      if (!id) return;
-      const name = $(".r-name", rc).value.trim();
-      if (name) contactsMap.set(id, name);
-      receivers.push({ id, message: $(".r-message", rc).value });
-    });
-    return {
-      name: $(".t-name", card).value.trim(),
-      mac: $(".t-mac", card).value.trim(),
-      enabled: $(".t-enabled", card).checked,
-      detection: { mode: $(".t-mode", card).value, ip: $(".t-ip", card).value.trim() },
-      message: $(".t-message", card).value,
-      receivers,
-    };
-  });
+  const targets = $all("#targets-list .target").map(card => ({
+    ...card.orig,
+    name: $(".t-name", card).value.trim(),
+    mac: $(".t-mac", card).value.trim(),
+    enabled: $(".t-enabled", card).checked,
+    detection: { mode: $(".t-mode", card).value, ip: $(".t-ip", card).value.trim() },
+    message: $(".t-message", card).value,
+    presence_policy: {
+      absence_reset_min: +$(".t-absence", card).value || 0,
+      departure_delay_min: +$(".t-departure", card).value || 0,
+      miss_threshold: +$(".t-misses", card).value || 0,
+    },
+    quiet_hours: collectQuietHours(card),
+    expect: collectExpect(card),
+    receivers: collectReceivers(card, contactsMap),
+  }));
+
+  const people = $all("#people-list .person").map(card => ({
+    ...card.orig,
+    name: $(".p-name", card).value.trim(),
+    enabled: $(".p-enabled", card).checked,
+    presence: $(".p-presence", card).value,
+    devices: $all(".p-device:checked", card).map(cb => cb.value),
+    message: $(".p-message", card).value,
+    departure_message: $(".p-departure", card).value,
+    receivers: collectReceivers(card, contactsMap),
+  }));
+
+  const household = {
+    ...currentTargets.household,
+    enabled: $("#hh-enabled").checked,
+    arrival_message: $("#hh-arrival").value,
+    empty_message: $("#hh-empty").value,
+    receivers: collectReceivers($("#household"), contactsMap),
+  };
+
+  const new_devices = collectNewDevicesConfig(contactsMap);
+  const security = collectSecurityConfig(contactsMap);
+  const admins = collectReceivers($("#admins"), contactsMap);
+  const batching = { window_sec: +$("#batch-window").value || 0, message: $("#batch-message").value };
 
   const contacts = [];
   contactsMap.forEach((name, id) => { if (name) contacts.push({ id, name }); });
 
-  return { default_message: $("#default-message").value, contacts, targets };
+  // Sections the UI doesn't edit (e.g. rules) are kept as loaded.
+  return {
+    ...currentTargets,
+    default_message: $("#default-message").value, contacts, targets, people, household, new_devices, security, admins, batching,
+  };
 }
 
 async function loadTargets() {
@@ -162,6 +320,112 @@ $("#add-target").addEventListener("click", () => {
   makeTarget({ enabled: true, detection: { mode: "auto", ip: "" }, receivers: [] });
 });
 $("#save-targets").addEventListener("click", saveTargets);
+$("#new-devices .t-add-receiver").addEventListener("click", () => makeReceiver($("#new-devices"), { id: "", message: "" }));
+$("#new-devices .t-pick").addEventListener("click", () => pickSeenUser($("#new-devices")));
+$("#security .t-add-receiver").addEventListener("click", () => makeReceiver($("#security"), { id: "", message: "" }));
+$("#security .t-pick").addEventListener("click", () => pickSeenUser($("#security")));
+$("#admins .t-add-receiver").addEventListener("click", () => makeReceiver($("#admins"), { id: "", message: "" }));
+$("#admins .t-pick").addEventListener("click", () => pickSeenUser($("#admins")));
+
+// ---------- rules ----------
+
+function describeAction(a) {
+  switch (a.type) {
+    case "notify":
+      return "Notify " + (a.receivers || []).map(r => r.id).join(", ") + ": " + a.message;
+    case "webhook":
+      return "POST " + a.url;
+    case "command":
+      return "Run " + (a.command || []).join(" ");
+  }
+  return a.type;
+}
+
+async function dryRunRules() {
+  const body = {
+    trigger: $("#rule-trigger").value,
+    subject: $("#rule-subject").value.trim(),
+    mac: $("#rule-mac").value.trim(),
+  };
+  const time = $("#rule-time").value;
+  if (time) body.time = new Date(time).toISOString();
+  try {
+    const results = await api("POST", "/api/rules/dry-run", body) || [];
+    const box = $("#rule-results");
+    box.innerHTML = results.length ? "" : '<div class="empty">No rules configured.</div>';
+    results.forEach(r => {
+      const div = document.createElement("div");
+      div.className = "rule-result";
+      div.innerHTML =
+        '<span class="badge ' + (r.matched ? "on" : "off") + '">' + (r.matched ? "runs" : "skipped") + "</span> " +
+        "<strong>" + escapeHtml(r.name) + "</strong>" +
+        (r.matched
+          ? "<ul>" + r.actions.map(a => "<li>" + escapeHtml(describeAction(a)) + "</li>").join("") + "</ul>"
+          : ' <span class="hint">' + escapeHtml(r.reason) + "</span>");
+      box.appendChild(div);
+    });
+  } catch (e) { toast("Dry run failed: " + e.message, "error"); }
+}
+
+$("#rule-dry-run").addEventListener("click", dryRunRules);
+
+// ---------- people ----------
+
+function makePerson(person) {
+  const node = $("#tpl-person").content.firstElementChild.cloneNode(true);
+  node.orig = person;
+  $(".p-enabled", node).checked = !!person.enabled;
+  $(".p-name", node).value = person.name || "";
+  $(".p-presence", node).value = person.presence || "any";
+  $(".p-message", node).value = person.message || "";
+  $(".p-departure", node).value = person.departure_message || "";
+
+  const owned = new Set((person.devices || []).map(m => m.toLowerCase()));
+  const devices = $(".p-devices", node);
+  (currentTargets.targets || []).forEach(t => {
+    const label = document.createElement("label");
+    label.className = "switch";
+    label.style.marginRight = "16px";
+    const cb = document.createElement("input");
+    cb.type = "checkbox";
+    cb.className = "p-device";
+    cb.value = t.mac;
+    cb.checked = owned.has((t.mac || "").toLowerCase());
+    const span = document.createElement("span");
+    span.textContent = t.name || t.mac;
+    label.append(cb, span);
+    devices.appendChild(label);
+  });
+  if (!devices.children.length) {
+    devices.innerHTML = '<div class="hint">Save some targets first.</div>';
+  }
+
+  $(".p-remove", node).addEventListener("click", () => node.remove());
+  $(".t-add-receiver", node).addEventListener("click", () => makeReceiver(node, { id: "", message: "" }));
+  $(".t-pick", node).addEventListener("click", () => pickSeenUser(node));
+
+  (person.receivers || []).forEach(r => makeReceiver(node, r));
+  $("#people-list").appendChild(node);
+}
+
+function renderPeople() {
+  $("#people-list").innerHTML = "";
+  (currentTargets.people || []).forEach(makePerson);
+
+  const hh = currentTargets.household || {};
+  $("#hh-enabled").checked = !!hh.enabled;
+  $("#hh-arrival").value = hh.arrival_message || "";
+  $("#hh-empty").value = hh.empty_message || "";
+  $("#household .receivers").innerHTML = "";
+  (hh.receivers || []).forEach(r => makeReceiver($("#household"), r));
+}
+
+$("#add-person").addEventListener("click", () => {
+  makePerson({ enabled: true, presence: "any", devices: [], receivers: [] });
+});
+$("#save-people").addEventListener("click", saveTargets);
+$("#household .t-add-receiver").addEventListener("click", () => makeReceiver($("#household"), { id: "", message: "" }));
+$("#household .t-pick").addEventListener("click", () => pickSeenUser($("#household")));
 
 // ---------- seen-user picker ----------
 
@@ -188,6 +452,7 @@ async function pickSeenUser(card) {
     item.style.cursor = "pointer";
     item.innerHTML =
       '<strong>' + (u.name ? escapeHtml(u.name) : "(unnamed)") + '</strong>' +
+      (u.kind === "group" || u.kind === "room" ? ' <span class="hint">' + (u.kind === "group" ? "group" : "multi-person chat") + '</span>' : "") +
       '<div class="rid">' + escapeHtml(u.id) + '</div>' +
       '<div class="hint">' + relTime(u.lastSeen) + '</div>';
     item.addEventListener("click", () => {
@@ -226,6 +491,11 @@ async function loadSystem() {
     $("#sys-bcast").value = s.arp_scan.broadcast_timeout_sec;
     $("#sys-indiv").value = s.arp_scan.individual_timeout_sec;
     $("#sys-absence").value = s.monitor.absence_reset_min;
+    $("#sys-departure").value = s.monitor.departure_delay_min;
+    $("#sys-misses").value = s.monitor.miss_threshold;
+    $("#sys-watchdog-cycles").value = s.watchdog.failed_cycles;
+    $("#sys-inventory").checked = !!s.inventory.enabled;
+    $("#sys-reverse-dns").checked = !!s.inventory.reverse_dns;
     $("#sys-host").value = s.server.host || "127.0.0.1";
     $("#sys-port").value = s.server.port;
   } catch (e) { toast("Failed to load system settings: " + e.message, "error"); }
@@ -240,7 +510,16 @@ async function saveSystem() {
       broadcast_timeout_sec: +$("#sys-bcast").value,
       individual_timeout_sec: +$("#sys-indiv").value,
     },
-    monitor: { absence_reset_min: +$("#sys-absence").value },
+    monitor: {
+      absence_reset_min: +$("#sys-absence").value,
+      departure_delay_min: +$("#sys-departure").value,
+      miss_threshold: +$("#sys-misses").value,
+    },
+    inventory: {
+      enabled: $("#sys-inventory").checked,
+      reverse_dns: $("#sys-reverse-dns").checked,
+    },
+    watchdog: { failed_cycles: +$("#sys-watchdog-cycles").value },
     server: { host: $("#sys-host").value, port: +$("#sys-port").value },
   };
   try {
@@ -253,33 +532,363 @@ $("#save-system").addEventListener("click", saveSystem);
 
 // ---------- status ----------
 
+function presenceBadge(home) {
+  return home
+    ? '<span class="badge on">Home</span>'
+    : '<span class="badge off">Away</span>';
+}
+
+function untilTime(iso) {
+  return iso ? " until " + new Date(iso).toLocaleString() : "";
+}
+
+function overrideBadges(o) {
+  if (!o) return "—";
+  const out = [];
+  const now = Date.now();
+  if (o.presence && (!o.until || new Date(o.until).getTime() > now)) {
+    out.push('<span class="badge on">Forced ' + (o.presence === "present" ? "home" : "away") + untilTime(o.until) + "</span>");
+  }
+  if (o.snoozeUntil && new Date(o.snoozeUntil).getTime() > now) {
+    out.push('<span class="badge off">Snoozed' + untilTime(o.snoozeUntil) + "</span>");
+  }
+  return out.join(" ") || "—";
+}
+
+// askUntil prompts for a duration in hours and returns the resulting time, ""
+// for no expiry (when allowed), or null when cancelled.
+function askUntil(label, allowForever) {
+  const answer = prompt(label + (allowForever ? " (hours, empty = until cleared)" : " (hours)"), allowForever ? "" : "24");
+  if (answer === null) return null;
+  if (answer.trim() === "" && allowForever) return "";
+  const hours = parseFloat(answer);
+  if (!(hours > 0)) { toast("Enter a positive number of hours", "error"); return null; }
+  return new Date(Date.now() + hours * 3600 * 1000).toISOString();
+}
+
+async function setOverride(action, mac) {
+  try {
+    if (action === "clear") {
+      await api("DELETE", "/api/overrides?mac=" + encodeURIComponent(mac));
+    } else if (action === "snooze") {
+      const until = askUntil("Snooze notifications for", false);
+      if (until === null) return;
+      await api("POST", "/api/overrides/snooze", { mac, until });
+    } else {
+      const until = askUntil("Force " + (action === "present" ? "home" : "away") + " for", true);
+      if (until === null) return;
+      await api("POST", "/api/overrides/presence", { mac, presence: action, until: until || undefined });
+    }
+    loadStatus();
+  } catch (e) { toast("Override failed: " + e.message, "error"); }
+}
+
+function renderDeviceStatus(rows) {
+  const tbody = $("#status-rows");
+  tbody.innerHTML = "";
+  $("#status-empty").classList.toggle("hidden", rows.length > 0);
+  rows.sort((a, b) => new Date(b.lastSeen) - new Date(a.lastSeen));
+  rows.forEach(r => {
+    const tr = document.createElement("tr");
+    let badge = r.notified
+      ? '<span class="badge on">Notified</span>'
+      : '<span class="badge off">Pending</span>';
+    if (r.quieted) badge = '<span class="badge off">Quiet hours</span>';
+    tr.innerHTML =
+      "<td>" + escapeHtml(r.name || "—") + "</td>" +
+      '<td class="rid">' + escapeHtml(r.mac) + "</td>" +
+      "<td>" + relTime(r.lastSeen) + "</td>" +
+      "<td>" + presenceBadge(r.present) + "</td>" +
+      "<td>" + badge + "</td>" +
+      "<td>" + overrideBadges(r.override) + "</td>" +
+      '<td><select class="ov-action">' +
+      '<option value="">Override…</option>' +
+      '<option value="present">Force home</option>' +
+      '<option value="absent">Force away</option>' +
+      '<option value="probe">Probe now</option>' +
+      '<option value="snooze">Snooze</option>' +
+      (r.override ? '<option value="clear">Clear</option>' : "") +
+      "</select></td>";
+    $(".ov-action", tr).addEventListener("change", e => {
+      const action = e.target.value;
+      e.target.value = "";
+      if (action === "probe") scanNow(r.mac);
+      else if (action) setOverride(action, r.mac);
+    });
+    tbody.appendChild(tr);
+  });
+}
+
+function renderPeopleStatus(rows) {
+  const tbody = $("#people-rows");
+  tbody.innerHTML = "";
+  $("#people-empty").classList.toggle("hidden", rows.length > 0);
+  rows.sort((a, b) => a.name.localeCompare(b.name));
+  rows.forEach(p => {
+    const tr = document.createElement("tr");
+    tr.innerHTML =
+      "<td>" + escapeHtml(p.name) + "</td>" +
+      "<td>" + presenceBadge(p.home) + "</td>" +
+      "<td>" + relTime(p.since) + "</td>" +
+      "<td>" + relTime(p.lastSeen) + "</td>";
+    tbody.appendChild(tr);
+  });
+}
+
+function renderNewDevices(rows) {
+  const tbody = $("#new-device-rows");
+  tbody.innerHTML = "";
+  $("#new-devices-empty").classList.toggle("hidden", rows.length > 0);
+  rows.forEach(d => {
+    const tr = document.createElement("tr");
+    tr.innerHTML =
+      '<td class="rid">' + escapeHtml(d.mac) + "</td>" +
+      "<td>" + escapeHtml(d.ip || "—") + "</td>" +
+      "<td>" + escapeHtml(d.vendor || "—") + "</td>" +
+      "<td>" + relTime(d.firstSeen) + "</td>" +
+      '<td><div class="inline">' +
+      '<button class="btn secondary small nd-target">Add as target</button>' +
+      '<button class="btn secondary small nd-known">Mark as known</button>' +
+      "</div></td>";
+    $(".nd-target", tr).addEventListener("click", () => resolveNewDevice("target", d));
+    $(".nd-known", tr).addEventListener("click", () => resolveNewDevice("known", d));
+    tbody.appendChild(tr);
+    if (focusNewDevice && d.mac.toLowerCase() === focusNewDevice) {
+      focusNewDevice = "";
+      tr.classList.add("focus");
+      tr.scrollIntoView({ block: "center" });
+    }
+  });
+}
+
+async function resolveNewDevice(action, d) {
+  const name = prompt("Name for " + d.mac + " (optional)", d.vendor || "");
+  if (name === null) return;
+  try {
+    await api("POST", "/api/new-devices/" + action, { mac: d.mac, name: name.trim() });
+    toast(action === "target" ? "Added as target" : "Marked as known", "ok");
+    await loadTargets();
+    loadStatus();
+  } catch (e) { toast("Failed: " + e.message, "error"); }
+}
+
+function renderOutbox(rows) {
+  const tbody = $("#outbox-rows");
+  tbody.innerHTML = "";
+  $("#outbox-empty").classList.toggle("hidden", rows.length > 0);
+  rows.forEach(e => {
+    const tr = document.createElement("tr");
+    const state = e.failed
+      ? '<span class="badge off">Failed</span>'
+      : (e.attempts > 0 ? '<span class="badge">Retry ' + new Date(e.nextAttempt).toLocaleTimeString() + "</span>" : '<span class="badge">Sending</span>');
+    tr.innerHTML =
+      '<td class="rid">' + escapeHtml(e.receiverId) + "</td>" +
+      "<td>" + escapeHtml(e.message.text) + "</td>" +
+      "<td>" + relTime(e.created) + "</td>" +
+      "<td>" + e.attempts + "</td>" +
+      "<td>" + state + "</td>" +
+      "<td>" + escapeHtml(e.lastError || "—") + "</td>" +
+      '<td><div class="inline">' +
+      (e.failed ? '<button class="btn secondary small ob-retry">Re-send</button>' : "") +
+      '<button class="btn danger small ob-discard">Discard</button>' +
+      "</div></td>";
+    if (e.failed) $(".ob-retry", tr).addEventListener("click", () => outboxAction("POST", "/api/outbox/retry", e.id));
+    $(".ob-discard", tr).addEventListener("click", () => outboxAction("DELETE", "/api/outbox", e.id));
+    tbody.appendChild(tr);
+  });
+}
+
+async function outboxAction(method, path, id) {
+  try {
+    await api(method, path + "?id=" + encodeURIComponent(id));
+    toast(method === "POST" ? "Queued for re-sending" : "Discarded", "ok");
+    loadStatus();
+  } catch (e) { toast("Failed: " + e.message, "error"); }
+}
+
 async function loadStatus() {
   try {
-    const rows = await api("GET", "/api/status");
-    const tbody = $("#status-rows");
-    tbody.innerHTML = "";
-    if (!rows || rows.length === 0) {
-      $("#status-empty").classList.remove("hidden");
-      return;
+    renderNewDevices(await api("GET", "/api/new-devices") || []);
+    renderOutbox(await api("GET", "/api/outbox") || []);
+    const status = await api("GET", "/api/overview");
+    renderDeviceStatus(status.devices || []);
+    renderPeopleStatus(status.people || []);
+    renderPause(status.pause || {});
+    const wd = status.watchdog || {};
+    $("#watchdog-status").innerHTML = wd.healthy
+      ? '<span class="badge on">Scans OK</span>'
+      : '<span class="badge off" title="' + escapeHtml(wd.problem || "") + '">Scans failing</span>';
+    const hh = status.household || {};
+    $("#household-status").innerHTML = hh.occupied
+      ? '<span class="badge on">House occupied</span>'
+      : '<span class="badge off">House empty</span>';
+    loadStats();
+  } catch (e) { toast("Failed to load status: " + e.message, "error"); }
+}
+
+// ---------- stats ----------
+
+let currentStats = [];
+
+function hours(min) {
+  return (min / 60).toFixed(1) + "h";
+}
+
+async function loadStats() {
+  try {
+    currentStats = await api("GET", "/api/stats") || [];
+    const sel = $("#stats-subject");
+    const keep = sel.value;
+    sel.innerHTML = currentStats.map((s, i) =>
+      '<option value="' + i + '">' + escapeHtml(s.name) + (s.kind === "person" ? "" : " (device)") + "</option>").join("");
+    if (keep && keep < currentStats.length) sel.value = keep;
+    renderStats();
+  } catch (e) { toast("Failed to load stats: " + e.message, "error"); }
+}
+
+function renderStats() {
+  const s = currentStats[+$("#stats-subject").value];
+  $("#stats-empty").classList.toggle("hidden", !!s);
+  if (!s) {
+    $("#stats-summary").innerHTML = "";
+    $("#stats-chart").innerHTML = "";
+    return;
+  }
+  const away = s.home ? "Home now" : "Away for " + hours(s.currentAwayMin);
+  $("#stats-summary").innerHTML = [
+    ["Today", hours(s.todayMin)],
+    ["7 days", hours(s.weekMin)],
+    ["30 days", hours(s.monthMin)],
+    ["Usual arrival", s.usualArrival || "—"],
+    ["Usual departure", s.usualDeparture || "—"],
+    ["Longest away", hours(s.longestAwayMin)],
+    ["Days away in a row", s.awayDays],
+    ["Now", away],
+  ].map(([k, v]) => '<div><span class="hint">' + k + "</span><strong>" + escapeHtml(String(v)) + "</strong></div>").join("");
+
+  // One bar per day, scaled to 24 hours.
+  const w = 12, gap = 3, h = 80;
+  const bars = s.daily.map((d, i) => {
+    const bh = Math.round(Math.min(d.minutes, 1440) / 1440 * h);
+    return '<rect x="' + i * (w + gap) + '" y="' + (h - bh) + '" width="' + w + '" height="' + bh + '" rx="2">' +
+      "<title>" + d.date + ": " + hours(d.minutes) + "</title></rect>";
+  }).join("");
+  $("#stats-chart").innerHTML =
+    '<svg viewBox="0 0 ' + s.daily.length * (w + gap) + " " + h + '" preserveAspectRatio="none">' + bars + "</svg>";
+}
+
+$("#stats-subject").addEventListener("change", renderStats);
+
+async function scanNow(target) {
+  const btn = $("#scan-now");
+  btn.disabled = true;
+  try {
+    const path = "/api/scan" + (target ? "?target=" + encodeURIComponent(target) : "");
+    const res = await api("POST", path);
+    const results = res.results || [];
+    if (target && results.length === 1) {
+      const r = results[0];
+      toast((r.name || r.mac) + ": " + (r.found ? "found via " + r.method + (r.ip ? " at " + r.ip : "") : "not found") +
+        " (" + r.durationMs + " ms)", r.found ? "ok" : "error");
+    } else {
+      const found = results.filter(r => r.found).length;
+      toast("Scan done: " + found + "/" + results.length + " found in " + (res.durationMs / 1000).toFixed(1) + "s", "ok");
     }
-    $("#status-empty").classList.add("hidden");
-    rows.sort((a, b) => new Date(b.lastSeen) - new Date(a.lastSeen));
-    rows.forEach(r => {
+    loadStatus();
+  } catch (e) { toast("Scan failed: " + e.message, "error"); }
+  finally { btn.disabled = false; }
+}
+
+$("#refresh-status").addEventListener("click", loadStatus);
+$("#scan-now").addEventListener("click", () => scanNow(""));
+
+function renderPause(p) {
+  $("#pause-status").innerHTML = p.paused
+    ? '<span class="badge off">Paused' + untilTime(p.until) + (p.reason ? " — " + escapeHtml(p.reason) : "") + "</span>"
+    : '<span class="badge on">Active</span>';
+}
+
+async function startPause() {
+  const until = $("#pause-until").value;
+  const body = { reason: $("#pause-reason").value };
+  if (until) body.until = new Date(until).toISOString();
+  try {
+    renderPause(await api("POST", "/api/pause", body));
+    toast("Notifications paused", "ok");
+  } catch (e) { toast("Pause failed: " + e.message, "error"); }
+}
+
+async function resumePause() {
+  try {
+    renderPause(await api("DELETE", "/api/pause"));
+    toast("Notifications resumed", "ok");
+  } catch (e) { toast("Resume failed: " + e.message, "error"); }
+}
+
+$("#pause-start").addEventListener("click", startPause);
+$("#pause-resume").addEventListener("click", resumePause);
+
+// ---------- network ----------
+
+async function loadNetwork() {
+  try {
+    const rows = await api("GET", "/api/devices") || [];
+    const tbody = $("#inventory-rows");
+    tbody.innerHTML = "";
+    $("#inventory-empty").classList.toggle("hidden", rows.length > 0);
+    rows.forEach(d => {
       const tr = document.createElement("tr");
-      const badge = r.notified
-        ? '<span class="badge on">Notified</span>'
-        : '<span class="badge off">Pending</span>';
+      const name = d.target
+        ? escapeHtml(d.name || "—") + ' <span class="badge on">Target</span>'
+        : escapeHtml(d.hostname || "—");
       tr.innerHTML =
-        "<td>" + escapeHtml(r.name || "—") + "</td>" +
-        '<td class="rid">' + escapeHtml(r.mac) + "</td>" +
-        "<td>" + relTime(r.lastSeen) + "</td>" +
-        "<td>" + badge + "</td>";
+        "<td>" + name + "</td>" +
+        '<td class="rid">' + escapeHtml(d.mac) + "</td>" +
+        "<td>" + escapeHtml((d.ips || []).join(", ")) + "</td>" +
+        "<td>" + escapeHtml(d.vendor || "—") + "</td>" +
+        "<td>" + relTime(d.firstSeen) + "</td>" +
+        "<td>" + relTime(d.lastSeen) + "</td>";
       tbody.appendChild(tr);
     });
-  } catch (e) { toast("Failed to load status: " + e.message, "error"); }
+  } catch (e) { toast("Failed to load inventory: " + e.message, "error"); }
 }
 
-$("#refresh-status").addEventListener("click", loadStatus);
+const conflictKinds = {
+  duplicate_ip: "Duplicate IP",
+  gateway_changed: "Gateway changed",
+  many_ips: "Many IPs",
+};
+
+async function loadConflicts() {
+  try {
+    const rows = await api("GET", "/api/security") || [];
+    const tbody = $("#conflict-rows");
+    tbody.innerHTML = "";
+    $("#conflicts-empty").classList.toggle("hidden", rows.length > 0);
+    rows.forEach(c => {
+      const tr = document.createElement("tr");
+      tr.innerHTML =
+        '<td><span class="badge off">' + escapeHtml(conflictKinds[c.kind] || c.kind) + "</span></td>" +
+        "<td>" + escapeHtml(c.ip || "—") + "</td>" +
+        '<td class="rid">' + escapeHtml((c.macs || []).join(", ")) + "</td>" +
+        "<td>" + escapeHtml((c.ips || []).join(", ") || "—") + "</td>" +
+        "<td>" + relTime(c.firstSeen) + "</td>" +
+        "<td>" + relTime(c.lastSeen) + "</td>";
+      tbody.appendChild(tr);
+    });
+  } catch (e) { toast("Failed to load conflicts: " + e.message, "error"); }
+}
+
+async function clearConflicts() {
+  if (!confirm("Clear all recorded conflicts?")) return;
+  try {
+    await api("DELETE", "/api/security");
+    loadConflicts();
+  } catch (e) { toast("Clear failed: " + e.message, "error"); }
+}
+
+$("#refresh-network").addEventListener("click", () => { loadNetwork(); loadConflicts(); });
+$("#clear-conflicts").addEventListener("click", clearConflicts);
 
 // ---------- init ----------
 
@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Delivery is one attempt at delivering to a webhook.
+type Delivery struct {
+	Webhook string    `json:"webhook"`
+	Time    time.Time `json:"time"`
+	Status  int       `json:"status"`          // HTTP status; 0 = no response
+	Error   string    `json:"error,omitempty"` // empty on success
+}
+
+const maxDeliveries = 100
+
+var (
+	deliveriesMu sync.Mutex
+	deliveries   []Delivery
+)
+
+// record appends an attempt, dropping the oldest beyond maxDeliveries.
+func record(d Delivery) {
+	deliveriesMu.Lock()
+	defer deliveriesMu.Unlock()
+	deliveries = append(deliveries, d)
+	if len(deliveries) > maxDeliveries {
+		deliveries = slices.Clone(deliveries[len(deliveries)-maxDeliveries:])
+	}
+}
+
+// Deliveries returns the recent delivery attempts, most recent first.
+func Deliveries() []Delivery {
+	deliveriesMu.Lock()
+	defer deliveriesMu.Unlock()
+	out := slices.Clone(deliveries)
+	slices.Reverse(out)
+	return out
+}
@@ -0,0 +1,156 @@
+// Package webhook delivers notifications to outgoing HTTP webhooks with a
+// templated body. Requests can be signed with HMAC-SHA256 over a timestamp
+// and the body, so receivers can verify them and reject replays.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+// Headers set on signed requests. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+const (
+	TimestampHeader = "X-Arp-Notify-Timestamp"
+	SignatureHeader = "X-Arp-Notify-Signature"
+)
+
+// Notifier posts to the webhooks named in the targets config. It reads the
+// config on every send, so webhook changes apply without a restart.
+type Notifier struct {
+	config func() map[string]config.WebhookConfig
+	client *http.Client
+}
+
+// New returns a notifier reading its webhooks from cfg.
+func New(cfg func() map[string]config.WebhookConfig) *Notifier {
+	return &Notifier{config: cfg, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// payload is the body sent when a webhook has no body template.
+type payload struct {
+	Text    string    `json:"text"`
+	Kind    string    `json:"kind,omitempty"`
+	Subject string    `json:"subject,omitempty"`
+	Mac     string    `json:"mac,omitempty"`
+	IP      string    `json:"ip,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+func render(w config.WebhookConfig, msg notifier.Message) ([]byte, error) {
+	t, err := w.Template()
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return json.Marshal(payload{msg.Text, msg.Kind, msg.Subject, msg.Mac, msg.IP, msg.Time})
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Sign returns the signature header value for a body sent at timestamp ts
+// (Unix seconds).
+func Sign(secret []byte, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Configured reports whether any webhook is set up.
+func (n *Notifier) Configured() bool {
+	return len(n.config()) > 0
+}
+
+// Send makes one delivery attempt to the named webhook, logged and recorded
+// in Deliveries. The outbox retries failures; responses other than 429 and
+// 5xx fail with notifier.ErrPermanent, as a retry would not help.
+func (n *Notifier) Send(ctx context.Context, name string, msg notifier.Message) error {
+	w, ok := n.config()[name]
+	if !ok {
+		return fmt.Errorf("no webhook named %q (%w)", name, notifier.ErrPermanent)
+	}
+	var secret []byte
+	if w.SecretEnv != "" {
+		if secret = []byte(os.Getenv(w.SecretEnv)); len(secret) == 0 {
+			return fmt.Errorf("webhook %q: %s is not set", name, w.SecretEnv)
+		}
+	}
+	body, err := render(w, msg)
+	if err != nil {
+		return fmt.Errorf("webhook %q: rendering body: %w", name, err)
+	}
+
+	status, err := n.post(ctx, w, secret, body)
+	record(Delivery{Webhook: name, Time: time.Now(), Status: status, Error: errString(err)})
+	if err != nil {
+		log.Printf("Webhook %q failed: %v", name, err)
+		if !retryable(status) {
+			return fmt.Errorf("webhook %q: %w (%w)", name, err, notifier.ErrPermanent)
+		}
+		return fmt.Errorf("webhook %q: %w", name, err)
+	}
+	log.Printf("Webhook %q: %d", name, status)
+	return nil
+}
+
+// post makes one request, returning the response status (0 if none came).
+func (n *Notifier) post(ctx context.Context, w config.WebhookConfig, secret, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, w.HTTPMethod(), w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+	if secret != nil {
+		// Signed afresh on every attempt, so retries carry a current timestamp.
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, Sign(secret, ts, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("returned %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed attempt may succeed later: no response
+// at all, rate limiting or a server error.
+func retryable(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
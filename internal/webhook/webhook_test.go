@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nekogravitycat/arp-notify/internal/config"
+	"github.com/nekogravitycat/arp-notify/internal/notifier"
+)
+
+func newNotifier(w config.WebhookConfig) *Notifier {
+	return New(func() map[string]config.WebhookConfig {
+		return map[string]config.WebhookConfig{"presence": w}
+	})
+}
+
+var mom = notifier.Message{
+	Text: "Mom is home", Kind: config.TriggerArrival, Subject: "Mom", IP: "192.168.0.7",
+	Time: time.Date(2026, 3, 6, 18, 0, 0, 0, time.UTC),
+}
+
+func TestSendRendersTemplateAndSigns(t *testing.T) {
+	t.Setenv("TEST_WEBHOOK_SECRET", "s3cret")
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	n := newNotifier(config.WebhookConfig{
+		URL:       srv.URL,
+		Method:    "put",
+		Headers:   map[string]string{"Authorization": "Bearer abc"},
+		Body:      `{"who": {{json .Subject}}, "event": {{json .Kind}}}`,
+		SecretEnv: "TEST_WEBHOOK_SECRET",
+	})
+	if err := n.Send(context.Background(), "presence", mom); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got.Method != http.MethodPut || got.Header.Get("Authorization") != "Bearer abc" {
+		t.Errorf("method %s, headers %v", got.Method, got.Header)
+	}
+	if want := `{"who": "Mom", "event": "arrival"}`; string(body) != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+	ts := got.Header.Get(TimestampHeader)
+	if ts == "" || got.Header.Get(SignatureHeader) != Sign([]byte("s3cret"), ts, body) {
+		t.Errorf("bad signature %q for timestamp %q", got.Header.Get(SignatureHeader), ts)
+	}
+}
+
+func TestSendDefaultsToJSON(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	if err := newNotifier(config.WebhookConfig{URL: srv.URL}).Send(context.Background(), "presence", mom); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	want := `{"text":"Mom is home","kind":"arrival","subject":"Mom","ip":"192.168.0.7","time":"2026-03-06T18:00:00Z"}`
+	if string(body) != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+}
+
+func TestSendServerErrorIsRetryable(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	err := newNotifier(config.WebhookConfig{URL: srv.URL}).Send(context.Background(), "presence", mom)
+	if err == nil || errors.Is(err, notifier.ErrPermanent) || calls != 1 {
+		t.Errorf("err = %v after %d calls, want one retryable failure", err, calls)
+	}
+	if d := Deliveries(); len(d) == 0 || d[0].Status != http.StatusBadGateway || d[0].Error == "" {
+		t.Errorf("deliveries = %+v", d)
+	}
+}
+
+func TestSendClientErrorIsPermanent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	err := newNotifier(config.WebhookConfig{URL: srv.URL}).Send(context.Background(), "presence", mom)
+	if err == nil || !strings.Contains(err.Error(), "bad payload") || !errors.Is(err, notifier.ErrPermanent) {
+		t.Errorf("err = %v, want a permanent error", err)
+	}
+}
+
+func TestSendRequiresSecret(t *testing.T) {
+	err := newNotifier(config.WebhookConfig{URL: "http://127.0.0.1:1", SecretEnv: "TEST_WEBHOOK_UNSET"}).
+		Send(context.Background(), "presence", mom)
+	if err == nil || !strings.Contains(err.Error(), "TEST_WEBHOOK_UNSET") {
+		t.Errorf("want a missing secret error, got %v", err)
+	}
+}
+
+func TestSendUnknownWebhookIsPermanent(t *testing.T) {
+	err := newNotifier(config.WebhookConfig{URL: "http://127.0.0.1:1"}).Send(context.Background(), "other", mom)
+	if !errors.Is(err, notifier.ErrPermanent) {
+		t.Errorf("err = %v, want a permanent error", err)
+	}
+}